@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	qc "github.com/linkthings/quick-convertor"
+)
+
+const initUsageTmpl = `
+Create a configuration file from the header of a csv file
+
+Usage: 
+  qc init -i csv file [-o config file]
+`
+
+// runInit runs the init command and returns the exit code
+func runInit(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	inputFile := flags.String("i", "", "Set the csv file to read the header from")
+	outputFile := flags.String("o", "", "Set the configuration file to create, print to the standard output if empty")
+	sampleRows := flags.Int("n", 100, "Set the number of records sampled to guess the int and float fields")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stdout, "%s", initUsageTmpl)
+		fmt.Fprintf(os.Stdout, "Flags:\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if *inputFile == "" || *sampleRows < 0 {
+		flags.Usage()
+		return exitFailure
+	}
+	if *outputFile != "" && qc.IsFile(*outputFile) {
+		errorf("configuration file %s already exists", *outputFile)
+		return exitFailure
+	}
+
+	// the csv file is decompressed, and the first csv member of a zip archive is read
+	inf, _, err := qc.OpenInputMember(*inputFile, "")
+	if err != nil {
+		errorf("%s", err)
+		return exitCode(err)
+	}
+	defer inf.Close()
+
+	content, err := qc.ScaffoldConfig(inf, *inputFile, *sampleRows)
+	if err != nil {
+		errorf("%s", err)
+		return exitCode(err)
+	}
+
+	if *outputFile == "" {
+		fmt.Print(string(content))
+		return 0
+	}
+	if err := os.WriteFile(*outputFile, content, 0644); err != nil {
+		errorf("unable to write configuration file: %s", err)
+		return exitOutput
+	}
+	return 0
+}
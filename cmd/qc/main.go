@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -10,6 +12,8 @@ import (
 	nested "github.com/antonfisher/nested-logrus-formatter"
 
 	"github.com/sirupsen/logrus"
+
+	qc "github.com/linkthings/quick-convertor"
 )
 
 const usageTmpl = `
@@ -17,28 +21,44 @@ Extract, filter and transform csv file into Microsoft Excel file
 
 Usage: 
   qc -c config file
+  qc validate -c config file [-i sample csv file]
+  qc init -i csv file [-o config file]
 `
 
 var versionStr = "1.0"
 var log = logrus.New()
 
+var errorf = func(arg string, v ...interface{}) {
+	log.Errorf(arg, v...)
+}
+
+// the exit codes for the different kinds of failures
 const (
-	LOG_DEBUG = 10
-	LOG_INFO  = 5
+	exitFailure = 1
+	exitConfig  = 2
+	exitInput   = 3
+	exitLookup  = 4
+	exitOutput  = 5
 )
 
-var infof = func(level int, format string, args ...interface{}) {
-	if *logLevel >= level {
-		log.Printf(format, args...)
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, qc.ErrConfigInvalid):
+		return exitConfig
+	case errors.Is(err, qc.ErrInputRead):
+		return exitInput
+	case errors.Is(err, qc.ErrLookupLoad):
+		return exitLookup
+	case errors.Is(err, qc.ErrOutputWrite):
+		return exitOutput
 	}
+	return exitFailure
 }
 
-var fatalf = func(arg string, v ...interface{}) {
-	log.Fatalf(arg, v...)
-}
-
-var errorf = func(arg string, v ...interface{}) {
-	log.Errorf(arg, v...)
+// exitOnError logs the error and exits with the code matching the kind of the error
+func exitOnError(err error) {
+	errorf("%s", err)
+	os.Exit(exitCode(err))
 }
 
 func showUsage() {
@@ -50,9 +70,10 @@ func showUsage() {
 var (
 	helpFlag    = flag.Bool("h", false, "Display the help menu")
 	versionFlag = flag.Bool("v", false, "Display version information")
-	logLevel    = flag.Int("l", 5, "Set the info level for troubleshooting")
+	logLevel    = flag.Int("l", qc.LOG_INFO, "Set the info level for troubleshooting")
 	warning     = flag.Bool("w", false, "Display the warning information")
 	inputFile   = flag.String("c", "./config.yaml", "Set the configuration file")
+	listFlag    = flag.Bool("list-converters", false, "List the field types available in the field definitions")
 
 	cpuProfile = flag.String("cpuprof", "", "Writes CPU profile to the specified file")
 	memProfile = flag.String("memprof", "", "Writes memory profile to the specified file")
@@ -69,6 +90,15 @@ func main() {
 		NoColors: false,
 	})
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "init":
+			os.Exit(runInit(os.Args[2:]))
+		}
+	}
+
 	flag.Parse()
 
 	cpuProfiling := false
@@ -98,16 +128,27 @@ func main() {
 		os.Exit(0)
 	}
 
-	ReadConfig(*inputFile)
+	// show the field types if requested
+	if *listFlag {
+		for _, name := range qc.ConverterNames() {
+			fmt.Println(name)
+		}
+		os.Exit(0)
+	}
 
-	err = ProcessCSVFile(config)
+	config, err := qc.ReadConfig(*inputFile)
+	if err != nil {
+		exitOnError(err)
+	}
+	logger := &qc.Logger{Out: log, Level: *logLevel, Warning: *warning}
+	pipeline, err := qc.NewPipeline(config, logger)
 	if err != nil {
-		fatalf("unable to process CSV: %s", err)
+		exitOnError(err)
 	}
 
-	//save the subFiles
-	for _, subFile := range config.Subfiles {
-		processSubFiles(subFile)
+	err = pipeline.RunFiles(context.Background())
+	if err != nil {
+		exitOnError(err)
 	}
 
 	if *memProfile != "" {
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	qc "github.com/linkthings/quick-convertor"
+)
+
+const validateUsageTmpl = `
+Check the configuration file without converting any file
+
+Usage: 
+  qc validate -c config file [-i sample csv or xlsx file]
+`
+
+// runValidate runs the validate command and returns the exit code
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	configFile := flags.String("c", "./config.yaml", "Set the configuration file")
+	sampleFile := flags.String("i", "", "Check the input fields against the header of the sample csv or xlsx file")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stdout, "%s", validateUsageTmpl)
+		fmt.Fprintf(os.Stdout, "Flags:\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	config, err := qc.ReadConfig(*configFile)
+	if err != nil {
+		fmt.Println(err)
+		return exitCode(err)
+	}
+
+	var header []string
+	if *sampleFile != "" {
+		// the sample is decompressed, and the first member of a zip archive is read
+		inf, name, err := qc.OpenInputMember(*sampleFile, config.InputMember)
+		if err != nil {
+			fmt.Println(err)
+			return exitCode(err)
+		}
+		defer inf.Close()
+
+		switch {
+		case qc.IsJSONFile(name):
+			// the fields of the JSON input are paths in the objects rather than header names
+		case qc.IsWorkbookFile(name):
+			header, err = qc.ReadSheetHeader(inf, config.InputSheet, config.HeaderRow)
+		default:
+			if input, decodeErr := qc.DecodeInput(inf, config.Encoding); decodeErr == nil {
+				// the unknown encoding is reported by the validation below
+				header, err = qc.ReadHeader(input, config.InputOptions)
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			return exitCode(err)
+		}
+	}
+
+	problems := qc.Validate(config, header)
+	for _, iter := range problems {
+		fmt.Println(iter)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%s: %d problem(s) found\n", *configFile, len(problems))
+		return exitConfig
+	}
+
+	fmt.Printf("%s: no problem found\n", *configFile)
+	return 0
+}
@@ -0,0 +1,198 @@
+package qc
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// the compression formats of the input, detected from the file extension or the magic bytes of the content
+const (
+	compressionNone = ""
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+	compressionZip  = "zip"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte{0x50, 0x4b, 0x03, 0x04}
+)
+
+// inputExt returns the lower case extension of the input file name, the extension of the gzip or zstd
+// compression is skipped, e.g. .csv for export.csv.gz
+func inputExt(fileName string) string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	switch ext {
+	case ".gz", ".gzip", ".zst", ".zstd":
+		return strings.ToLower(filepath.Ext(strings.TrimSuffix(fileName, filepath.Ext(fileName))))
+	}
+	return ext
+}
+
+// inputMember is a decompressed content of an input file, either the whole file or a member of the zip archive
+type inputMember struct {
+	fileName string // the input file name, followed by / and the member name for the zip archive
+	open     func() (io.ReadCloser, error)
+}
+
+// decompressInput detects the compression of the input file from its extension or the magic bytes, and returns
+// the decompressed content: the gzip and zstd content is decompressed while being read, and the members of
+// the zip archive matching the memberName pattern, or all the csv, JSON and xlsx members if memberName is empty,
+// are returned in turn. The xlsx workbook is not taken as a zip archive.
+func decompressInput(input io.Reader, fileName string, memberName string) ([]inputMember, error) {
+	compression := compressionNone
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gz", ".gzip":
+		compression = compressionGzip
+	case ".zst", ".zstd":
+		compression = compressionZstd
+	case ".zip":
+		compression = compressionZip
+	}
+
+	buf := bufio.NewReader(input)
+	if compression == compressionNone && !IsWorkbookFile(fileName) {
+		magic, _ := buf.Peek(len(zipMagic))
+		switch {
+		case bytes.HasPrefix(magic, gzipMagic):
+			compression = compressionGzip
+		case bytes.HasPrefix(magic, zstdMagic):
+			compression = compressionZstd
+		case bytes.HasPrefix(magic, zipMagic):
+			compression = compressionZip
+		}
+	}
+
+	switch compression {
+	case compressionGzip:
+		return []inputMember{{fileName: fileName, open: func() (io.ReadCloser, error) {
+			return gzip.NewReader(buf)
+		}}}, nil
+	case compressionZstd:
+		return []inputMember{{fileName: fileName, open: func() (io.ReadCloser, error) {
+			decoder, err := zstd.NewReader(buf)
+			if err != nil {
+				return nil, err
+			}
+			return decoder.IOReadCloser(), nil
+		}}}, nil
+	case compressionZip:
+		return zipMembers(input, buf, fileName, memberName)
+	}
+	return []inputMember{{fileName: fileName, open: func() (io.ReadCloser, error) {
+		return io.NopCloser(buf), nil
+	}}}, nil
+}
+
+// OpenInputMember opens the input file at path, or the standard input if path is empty, and returns the
+// decompressed content of the file, or of the first member matching the memberName pattern if it is a zip archive,
+// with the name of the content, e.g. export.zip/data.csv. It is used to read the header of a sample input.
+func OpenInputMember(path string, memberName string) (io.ReadCloser, string, error) {
+	f, err := OpenInput(path)
+	if err != nil {
+		return nil, "", err
+	}
+	members, err := decompressInput(f, path, memberName)
+	if err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	content, err := members[0].open()
+	if err != nil {
+		f.Close()
+		return nil, "", &Error{Kind: ErrInputRead, File: members[0].fileName, Err: err}
+	}
+	return &memberContent{ReadCloser: content, file: f}, members[0].fileName, nil
+}
+
+// memberContent is the decompressed content which closes the input file as well
+type memberContent struct {
+	io.ReadCloser
+	file io.Closer
+}
+
+func (m *memberContent) Close() error {
+	err := m.ReadCloser.Close()
+	if fileErr := m.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
+// zipMembers returns the members of the zip archive, the archive is read into memory if input is not a file
+func zipMembers(input io.Reader, buf *bufio.Reader, fileName string, memberName string) ([]inputMember, error) {
+	var content io.ReaderAt
+	var size int64
+	if f, ok := input.(*os.File); ok {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, &Error{Kind: ErrInputRead, File: fileName, Err: err}
+		}
+		if info.Mode().IsRegular() {
+			content, size = f, info.Size()
+		}
+	}
+	if content == nil {
+		data, err := io.ReadAll(buf)
+		if err != nil {
+			return nil, &Error{Kind: ErrInputRead, File: fileName, Err: err}
+		}
+		content, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	archive, err := zip.NewReader(content, size)
+	if err != nil {
+		return nil, &Error{Kind: ErrInputRead, File: fileName, Err: err}
+	}
+
+	members := make([]inputMember, 0)
+	for _, iter := range archive.File {
+		if iter.FileInfo().IsDir() {
+			continue
+		}
+		if memberName != "" {
+			matched, err := path.Match(memberName, iter.Name)
+			if err != nil {
+				return nil, &Error{Kind: ErrConfigInvalid, File: fileName, Err: fmt.Errorf("inputMember %s: %w", memberName, err)}
+			}
+			if !matched {
+				continue
+			}
+		} else if !isInputMember(iter.Name) {
+			continue
+		}
+		members = append(members, inputMember{fileName: fileName + "/" + iter.Name, open: iter.Open})
+	}
+	if len(members) == 0 {
+		if memberName != "" {
+			return nil, &Error{Kind: ErrInputRead, File: fileName, Err: fmt.Errorf("no member matches %s in the zip archive", memberName)}
+		}
+		return nil, &Error{Kind: ErrInputRead, File: fileName, Err: errors.New("no csv, JSON or xlsx member in the zip archive")}
+	}
+	return members, nil
+}
+
+// isInputMember reports whether the member of the zip archive is a csv, JSON or xlsx file, the files in the
+// folders made by macOS are skipped
+func isInputMember(name string) bool {
+	if strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv", ".tsv", ".txt":
+		return true
+	}
+	return IsJSONFile(name) || IsWorkbookFile(name)
+}
@@ -1,8 +1,9 @@
-package main
+package qc
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -11,29 +12,118 @@ import (
 )
 
 type CSVConvertorConfig struct {
-	Input     string     `config:"input"`
-	Output    string     `config:"output"`
-	SheetName string     `config:"sheetName"`
-	Fields    []string   `config:"fields"`
-	Subfiles  []*SubFile `config:"subfile"`
-	Lookups   []*Lookup  `config:"lookup"`
-	Filters   []*Filter  `config:"filter"`
-	// below attributes to keep the converted result
-	fieldSlice  []*Field          // define the output fields setting based on the Fields configs
-	fieldsMap   map[string]*Field // memory map for quick references to fieldSlice,using input csv's field name as key
-	subfilesMap map[string]*SubFile
-	lookupMap   map[string]*Lookup
+	Input           InputFiles     `config:"input"`
+	Output          string         `config:"output"`
+	SheetName       string         `config:"sheetName"`
+	InputSheet      string         `config:"inputSheet"`  // the sheet of the xlsx input, the first sheet if empty
+	HeaderRow       int            `config:"headerRow"`   // the header row number of the xlsx input starting from 1, the first non-empty row if 0
+	HasHeader       *bool          `config:"hasHeader"`   // the first record of the input is the header, true if not set
+	Encoding        string         `config:"encoding"`    // the text encoding of the csv and JSON input, UTF-8 or detected from the byte order mark if empty
+	InputMember     string         `config:"inputMember"` // the member of the zip input, which can be a pattern, all the csv, JSON and xlsx members if empty
+	InputOptions    InputOptions   `config:"inputOptions"`
+	HeaderMatch     string         `config:"headerMatch"`     // how the header names are compared with the input field names: exact, trimmed or caseInsensitive
+	StrictHeader    bool           `config:"strictHeader"`    // abort the run if a required input field is not found in the header
+	SourceFileField string         `config:"sourceFileField"` // the input field holding the name of the file each record is read from
+	Format          string         `config:"format"`          // the output format: xlsx, csv, tsv, jsonl or sqlite, detected from the output file extension if empty
+	FuncCells       string         `config:"funcCells"`       // write the func fields as formula or empty in the csv, tsv, jsonl and sqlite output
+	TableMode       string         `config:"tableMode"`       // replace or append to the existing table in the sqlite output
+	Fields          []*FieldConfig `config:"fields"`
+	Subfiles        []*SubFile     `config:"subfile"`
+	Lookups         []*Lookup      `config:"lookup"`
+	Filters         []*Filter      `config:"filter"`
+}
+
+// hasHeader reports whether the first record of the input is the header
+func (c *CSVConvertorConfig) hasHeader() bool {
+	return c.HasHeader == nil || *c.HasHeader
+}
+
+// InputFiles is the list of the input files, in which a file name can be a glob pattern, e.g. data/export-*.csv.
+// It is either a single file name or a list of file names in the config file
+type InputFiles []string
+
+// Unpack implements ucfg.Unpacker to accept both a file name and a list of file names
+func (f *InputFiles) Unpack(in interface{}) error {
+	switch value := in.(type) {
+	case string:
+		*f = InputFiles{value}
+		return nil
+	case []interface{}:
+		files := make(InputFiles, 0, len(value))
+		for _, iter := range value {
+			name, ok := iter.(string)
+			if !ok {
+				return fmt.Errorf("input file name must be a string, got: %v", iter)
+			}
+			files = append(files, name)
+		}
+		*f = files
+		return nil
+	}
+	return fmt.Errorf("input must be a file name or a list of file names, got: %v", in)
+}
+
+// first returns the first input file name, empty if there is no input file
+func (f InputFiles) first() string {
+	if len(f) == 0 {
+		return ""
+	}
+	return f[0]
+}
+
+// Expand returns the input file names with the glob patterns replaced by the matching file names in lexical order.
+// A pattern matching no file is an error, while the plain file names are returned as they are
+func (f InputFiles) Expand() ([]string, error) {
+	result := make([]string, 0, len(f))
+	for _, iter := range f {
+		if !strings.ContainsAny(iter, "*?[") {
+			result = append(result, iter)
+			continue
+		}
+		matches, err := filepath.Glob(iter)
+		if err != nil {
+			return nil, &Error{Kind: ErrConfigInvalid, File: iter, Err: err}
+		}
+		if len(matches) == 0 {
+			return nil, &Error{Kind: ErrInputRead, File: iter, Err: errors.New("no file matches the pattern")}
+		}
+		result = append(result, matches...)
+	}
+	return result, nil
+}
+
+// InputOptions defines the dialect of the csv input
+type InputOptions struct {
+	Delimiter        string `config:"delimiter"`        // the field delimiter, `,` if empty, use "tab" or "\t" for tab
+	Comment          string `config:"comment"`          // the lines starting with the comment character are ignored
+	LazyQuotes       bool   `config:"lazyQuotes"`       // allow the bare quotes in the unquoted fields and the quotes in the quoted fields
+	TrimLeadingSpace bool   `config:"trimLeadingSpace"` // ignore the leading white space of the fields
+	VariableFields   bool   `config:"variableFields"`   // allow the records with different field counts, the short records are filled up with empty values
+	SkipRows         int    `config:"skipRows"`         // the number of lines above the header to skip
 }
 
 type SubFile struct {
-	Name      string   `config:"name"`
-	SheetName string   `config:"sheetName"`
-	Output    string   `config:"output"`
-	Fields    []string `config:"fields"`
+	Name         string         `config:"name"`
+	SheetName    string         `config:"sheetName"`
+	Output       string         `config:"output"`
+	Format       string         `config:"format"`
+	FuncCells    string         `config:"funcCells"`
+	TableMode    string         `config:"tableMode"`
+	Nested       bool           `config:"nested"`    // nest the rows as an array under the parent record in the jsonl output
+	Separator    string         `config:"separator"` // split the subfile values into the parts referred by the value[N] fields
+	Preset       string         `config:"preset"`    // the built-in way to split the subfile values, e.g. JiraLogTime
+	Fields       []*FieldConfig `config:"fields"`
+	Filters      []*Filter      `config:"filter"`       // keep the subfile rows matching the filters only
+	FollowFilter bool           `config:"followFilter"` // drop the rows of the master records which are filtered out by the main filters
 	// below attributes to keep the converted result
 	fieldsMap  map[string]*Field
 	fieldSlice []*Field
-	records    [][]string
+	writer     RecordWriter // the output of the current run
+	saveCount  int
+	separator  string // the separator of the subfile values, empty if not split
+	minParts   int    // the values with fewer parts are skipped
+	parts      []int  // the parts of the value fields in order, 0 for the whole value
+	filters    []*Filter
 }
 
 type Lookup struct {
@@ -49,7 +139,6 @@ type Lookup struct {
 	keyValueSlice []*LookupRegex // for substring and regex
 	converterType ConverterType
 	converter     Converter
-	err           error
 }
 
 type Filter struct {
@@ -78,176 +167,180 @@ type Field struct {
 	Params []interface{}
 
 	// the position of the field in the input csv file, this is dynamic calculated when reading the input file
-	inputPos      int    // the position of the field in the input CSV file; default is -1
-	inputPosArray []int  // the position array of the fields (with same name) in the input CSV file
-	value         string // the actual field value in the csv file, save for temp use
+	inputPos      int            // the position of the field in the input CSV file; default is -1
+	inputPosArray []int          // the position array of the fields (with same name) in the input CSV file
+	value         string         // the actual field value in the csv file, save for temp use
+	header        *headerMatcher // match the header names of the input field, nil if the field is not read from the input
+	required      bool           // the input field must be found in the header
+	column        int            // the column of the positional input name, e.g. #3 or $C, -1 if the input is referred by name
+	masterPos     int            // the position of the master output field referred by the subfile field in the converted master record, -1 if none
 	converterType ConverterType
 	converter     Converter
+	log           *Logger
 }
 
-var config = CSVConvertorConfig{}
-
-func ReadConfig(path string) {
+// ReadConfig loads the configuration file at path
+func ReadConfig(path string) (*CSVConvertorConfig, error) {
+	config := new(CSVConvertorConfig)
 
 	configRaw, err := yaml.NewConfigWithFile(path, ucfg.PathSep("."))
 	if err != nil {
-		errorf("ReadConfig NewConfigWithFile return: %s", err)
-		os.Exit(1)
+		return nil, &Error{Kind: ErrConfigInvalid, File: path, Err: err}
 	}
-	err = configRaw.Unpack(&config)
+	err = configRaw.Unpack(config)
 	if err != nil {
-		errorf("ReadConfig Unpack return: %s", err)
-		os.Exit(1)
+		return nil, &Error{Kind: ErrConfigInvalid, File: path, Err: err}
 	}
 
-	config.subfilesMap = make(map[string]*SubFile)
-	config.lookupMap = make(map[string]*Lookup)
-	// read the mapping file and store the mapping in memory
-	for _, iter := range config.Lookups {
-		// update the LookupOption
-		iter.lookupOption = LookupOptionConvert(iter.Option)
-		iter.converterType, iter.converter = FieldTypeConvert(iter.Type)
-		iter.keyValueMap = make(map[string][]string)
-		iter.keyValueSlice = make([]*LookupRegex, 0)
-
-		infof(6, "Lookup[%s] with option: %s", iter.Name, iter.lookupOption.String())
-		infof(6, "loadExcelFile processing lookup[%s] in file: %s, sheet: %s", iter.Name, iter.FileName, iter.SheetName)
-		//save the load result, keep the return error string into the iter.err thus the error can be output to the resulting file
-		iter.err = loadLookupExcelFile(iter)
-		config.lookupMap[iter.Name] = iter
-	}
-
-	// processing the subFiles
-	for _, iter := range config.Subfiles {
-		iter.fieldsMap, iter.fieldSlice = formalizeFieldConfigs(iter.Fields)
-		// update the field location id directly
-		for id, field := range iter.fieldSlice {
-			if field.InputName != "" {
-				field.inputPos = id
-			}
-		}
-		//add the subFile name to map
-		config.subfilesMap[iter.Name] = iter
-		iter.records = make([][]string, 0)
-		infof(6, "ReadConfig add subFile [%s]", iter.Name)
-	}
-
-	config.fieldsMap, config.fieldSlice = formalizeFieldConfigs(config.Fields)
-
-	// processing the filters
-	for _, iter := range config.Filters {
-		iter.fieldPos = getOutputFieldPos(iter.Field, config.fieldSlice)
-		if iter.fieldPos >= 0 {
-			iter.valueMap = make(map[string]int)
-			for _, field := range iter.Values {
-				iter.valueMap[field] = 1
-			}
-		} else {
-			errorf("filter field [%s] is not defined in the field list", iter.Field)
-		}
-	}
-
-	infof(3, "ReadConfig load %s successfully", path)
+	return config, nil
 }
 
-func formalizeFieldConfigs(csvFields []string) (map[string]*Field, []*Field) {
+// formalizeFieldConfigs creates the fields of the field definitions, path is the YAML path of the field list
+// used in the returned *Error
+func (p *Pipeline) formalizeFieldConfigs(path string, fieldConfigs []*FieldConfig) (map[string]*Field, []*Field, error) {
 	fieldsMap := make(map[string]*Field)
 	fieldSlice := make([]*Field, 0)
 
-	for _, iter := range csvFields {
-		fields := strings.Split(iter, ",")
-		if len(fields) < 2 {
-			errorf("incorrect field format, the %s must have at least 2 parameters separated by `,`", iter)
+	for id, iter := range fieldConfigs {
+		if iter.err != nil {
+			p.log.errorf("incorrect field format %s: %s", iter.definition, iter.err)
 			continue
 		}
 		field := new(Field)
 		field.inputPos = -1
 		field.inputPosArray = make([]int, 0)
-		field.InputName = strings.TrimSpace(fields[0])
-		field.OutputName = strings.TrimSpace(fields[1])
-		field.Width = 20
-		//pass the default converter
-		field.converterType, field.converter = FieldTypeConvert("")
+		field.InputName = iter.Input
+		field.OutputName = iter.Output
+		field.Width = iter.Width
+		field.Type = iter.Type
+		field.required = iter.Required
+		field.column = inputColumn(field.InputName)
+		field.masterPos = -1
+		field.log = p.log
+		field.converterType, field.converter = FieldTypeConvert(field.Type)
 		if field.InputName != "" {
 			fieldsMap[field.InputName] = field
 		}
 
-		size := len(fields)
+		var err error
 
-		if size >= 3 {
-			field.Width, _ = strconv.Atoi(strings.TrimSpace(fields[2]))
-		}
-		if size >= 4 {
-			field.Type = strings.TrimSpace(fields[3])
-			field.converterType, field.converter = FieldTypeConvert(field.Type)
-		}
+		size := len(iter.Params)
 
-		var err error
+		field.Params = make([]interface{}, 0)
+		switch field.converterType {
+		case ConverterTypeSubfile, ConverterTypeExplode:
+			if size == 0 {
+				err = errors.New("missing subfile name")
+				break
+			}
+			field.Params = append(field.Params, strings.TrimSpace(iter.Params[0]))
+			if field.converterType == ConverterTypeExplode {
+				field.Params = append(field.Params, defaultExplodeSeparator)
+				if size >= 2 && iter.Params[1] != "" {
+					field.Params[1] = iter.Params[1]
+				}
+			}
+		case ConverterTypeFunc:
+			if size == 0 {
+				err = fmt.Errorf("missing parameter for type %s", field.Type)
+				break
+			}
+			// need to pull all the remaining fields together
+			field.Params = append(field.Params, strings.Join(iter.Params, ","))
+		case ConverterTypeConstantString:
+			if size == 0 {
+				err = fmt.Errorf("missing parameter for type %s", field.Type)
+				break
+			}
+			field.Params = append(field.Params, strings.TrimSpace(strings.Join(iter.Params, ",")))
+		case ConverterTypeLookup:
+			if size != 3 {
+				err = fmt.Errorf("invalid parameter size %d for lookup, should have: src index, map name, result index", size)
+				break
+			}
+			ref := strings.TrimSpace(iter.Params[0])
+			name := strings.TrimSpace(iter.Params[1])
+			// get the index value based on the reference field's output name
+			mapIndex, atoiErr := strconv.Atoi(strings.TrimSpace(iter.Params[2]))
+			// convert the src index to map index (need to reduce 2 as the map key is not included in the result list)
+			mapIndex -= 2
+			refIndex := getOutputFieldPos(ref, fieldSlice)
+			lookup := p.lookupMap[name]
+
+			switch {
+			case atoiErr != nil || mapIndex < 0:
+				err = fmt.Errorf("incorrect map index value %s, should be a number starting from 2", strings.TrimSpace(iter.Params[2]))
+			case lookup == nil:
+				// can't find stored lookup map
+				err = fmt.Errorf("undefined lookup map %s", name)
+			case refIndex == -1:
+				err = fmt.Errorf("the referenced field [%s] is not defined prior to the current field", ref)
+			default:
+				field.Params = append(field.Params, refIndex, lookup, mapIndex)
+			}
+		}
 
-		if size >= 5 {
-			field.Params = make([]interface{}, 0)
-			switch field.converterType {
-			case ConverterTypeSubfile:
-				field.Params = append(field.Params, strings.TrimSpace(fields[4]))
-			case ConverterTypeFunc:
-				// need to pull all the remaining fields together
-				field.Params = append(field.Params, strings.Join(fields[4:], ","))
-			case ConverterTypeLookup:
-				if size != 7 {
-					//for errors, change it to be a default converter and export the constant string in the output
-					errorf("Processing field [%s]: invalid parameter size for ConverterTypeLookup, should have: src index, map name, result index", field.OutputName)
-					err = errors.New("invalid parameter size for " + field.OutputName)
-				} else {
-					// get the index value based on the reference field's output name
-					var mapIndex int
-					if mapIndex, err = strconv.Atoi(strings.TrimSpace(fields[6])); err == nil {
-						mapIndex -= 2 // convert the src index to map index (need to reduce 2 as the map key is not included in the result list)
-						if mapIndex < 0 {
-							err = errors.New("incorrect map index value " + strings.TrimSpace(fields[5]))
-						}
-					}
-					refIndex := getOutputFieldPos(strings.TrimSpace(fields[4]), fieldSlice)
-					lookup := config.lookupMap[strings.TrimSpace(fields[5])]
-
-					if err == nil {
-						if lookup != nil {
-							err = lookup.err
-						} else {
-							// can't find stored lookup map
-							err = errors.New("undefined lookup map " + strings.TrimSpace(fields[5]))
-						}
-					}
-					if err == nil && refIndex == -1 {
-						err = errors.New("the field not defined yet" + fields[4])
-					}
-					if err == nil {
-						field.Params = append(field.Params, refIndex)
-						field.Params = append(field.Params, lookup)
-						field.Params = append(field.Params, mapIndex)
-					}
+		if err == nil && field.converterType == ConverterTypeDefault && field.Type != "" {
+			// not a built-in type, create the converter if the type is registered
+			params := make([]string, 0)
+			for _, param := range iter.Params {
+				params = append(params, strings.TrimSpace(param))
+			}
+			var converter Converter
+			var ok bool
+			if converter, ok, err = newCustomConverter(field.Type, params); ok && err == nil {
+				field.converterType = ConverterTypeCustom
+				field.converter = converter
+				for _, param := range params {
+					field.Params = append(field.Params, param)
 				}
 			}
 		}
 
+		if err == nil {
+			field.header, err = newHeaderMatcher(iter, p.config.HeaderMatch)
+		}
+
 		if err != nil {
-			// for any error, keep adding the field, but convey the error into the resulting file
-			errorf("Processing field: %s return error: %s", field.OutputName, err)
-			field.converterType = ConverterTypeConstantString
-			field.converter = converterConstantString
-			field.Params = append(field.Params, err.Error())
+			return nil, nil, &Error{Kind: ErrConfigInvalid, Path: fmt.Sprintf("%s.%d", path, id), Err: err}
 		}
 
 		fieldSlice = append(fieldSlice, field)
 	}
-	return fieldsMap, fieldSlice
+	return fieldsMap, fieldSlice, nil
+}
+
+// isSubfile reports whether the field writes its values into the subfile rows rather than the output column
+func (f *Field) isSubfile() bool {
+	return f.converterType == ConverterTypeSubfile || f.converterType == ConverterTypeExplode
+}
+
+// hasOutput reports whether the field writes a column in the output, the subfile fields write their values
+// into the subfile rows only, even if they have an output name
+func (f *Field) hasOutput() bool {
+	return f.OutputName != "" && !f.isSubfile()
+}
+
+// logger returns the Logger of the pipeline which the field belongs to, nil if the field is nil
+func (f *Field) logger() *Logger {
+	if f == nil {
+		return nil
+	}
+	return f.log
 }
 
-// this function is used for the lookup func as it need to find the position for a output field
+// this function is used for the lookup func as it need to find the position for a output field.
+// The position is counted in the converted item list, in which the fields without output column take no place
 func getOutputFieldPos(fieldName string, fieldSlice []*Field) int {
-	for id, iter := range fieldSlice {
+	pos := 0
+	for _, iter := range fieldSlice {
+		if !iter.hasOutput() {
+			continue
+		}
 		if iter.OutputName == fieldName {
-			return id
+			return pos
 		}
+		pos++
 	}
 	return -1
 }
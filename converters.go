@@ -1,9 +1,11 @@
-package main
+package qc
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type Converter func(itemData *[]interface{}, input string, field *Field) (result *string)
@@ -20,12 +22,79 @@ const (
 	ConverterTypeFunc
 	ConverterTypeLookup
 	ConverterTypeConstantString
+	ConverterTypeCustom
+	ConverterTypeExplode
 )
 
+// defaultExplodeSeparator splits the cells of the explode fields if the separator is not defined
+const defaultExplodeSeparator = ";"
+
 var converterError string = "invalid parameter in config file"
 
 func (ft ConverterType) String() string {
-	return []string{"default", "sec2day", "sec2hour", "float", "int", "time2date", "subfile", "func", "lookup", "constant"}[ft]
+	return []string{"default", "sec2day", "sec2hour", "float", "int", "time2date", "subfile", "func", "lookup", "constant", "custom", "explode"}[ft]
+}
+
+// ConverterFactory creates the Converter for a field whose type is registered by RegisterConverter.
+// The params are the remaining parameters after the type in the field definition, an error of the factory
+// fails NewPipeline as an invalid config.
+type ConverterFactory func(params []string) (Converter, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]ConverterFactory)
+)
+
+// builtinConverters are the field types handled by FieldTypeConvert, which cannot be registered again
+var builtinConverters = []string{"sec2day", "sec2hour", "float", "int", "time2date", "subfile", "explode", "func", "lookup", "constant"}
+
+// RegisterConverter makes a custom field type available by name in the field definitions of all the configs.
+// The name is case insensitive. It panics if the factory is nil or the name is already in use,
+// thus it is expected to be called from an init function.
+func RegisterConverter(name string, factory ConverterFactory) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if factory == nil {
+		panic("qc: RegisterConverter factory is nil for " + name)
+	}
+	if name == "" {
+		panic("qc: RegisterConverter name is empty")
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, dup := registry[name]; dup || containsString(builtinConverters, name) {
+		panic("qc: RegisterConverter called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// ConverterNames returns the sorted names of the built-in and registered field types
+func ConverterNames() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := append([]string{}, builtinConverters...)
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCustomConverter creates the converter of a registered field type, ok is false if the name is not registered
+func newCustomConverter(name string, params []string) (converter Converter, ok bool, err error) {
+	registryMutex.RLock()
+	factory := registry[strings.ToLower(name)]
+	registryMutex.RUnlock()
+
+	if factory == nil {
+		return nil, false, nil
+	}
+	converter, err = factory(params)
+	if err == nil && converter == nil {
+		err = fmt.Errorf("converter factory of %s returns nil converter", name)
+	}
+	return converter, true, err
 }
 
 // FieldTypeConvert convert the string values from the configuration file into internal enum and function values
@@ -52,6 +121,9 @@ func FieldTypeConvert(input string) (ft ConverterType, ct Converter) {
 	case "subfile":
 		ft = ConverterTypeSubfile
 		ct = converterSubfile
+	case "explode":
+		ft = ConverterTypeExplode
+		ct = converterSubfile
 	case "func":
 		ft = ConverterTypeFunc
 		ct = converterFunc
@@ -72,7 +144,7 @@ func converterDefault(itemData *[]interface{}, input string, field *Field) (resu
 
 func converterConstantString(itemData *[]interface{}, input string, field *Field) (result *string) {
 	if field == nil || len(field.Params) != 1 {
-		errorf("converterConstantString invalid parameter in Field, return nil")
+		field.logger().errorf("converterConstantString invalid parameter in Field, return nil")
 		result = &converterError
 		*itemData = append(*itemData, converterError)
 		return result
@@ -141,11 +213,19 @@ func converterFunc(itemData *[]interface{}, input string, field *Field) (result
 	return nil
 }
 
+// itemString returns the text of a converted item, e.g. the number converted by sec2hour
+func itemString(item interface{}) string {
+	if item == nil {
+		return ""
+	}
+	return fmt.Sprint(item)
+}
+
 func converterLookup(itemData *[]interface{}, input string, field *Field) (result *string) {
 	var resLookup string
 
 	if field == nil || len(field.Params) != 3 {
-		errorf("converterLookup invalid parameter in Field, return nil")
+		field.logger().errorf("converterLookup invalid parameter in Field, return nil")
 		result = &converterError
 		*itemData = append(*itemData, converterError)
 		return result
@@ -160,17 +240,18 @@ func converterLookup(itemData *[]interface{}, input string, field *Field) (resul
 	lenItemData := len(*itemData)
 
 	// the input is empty in this case
-	infof(10, "converterLookup for field [%s], itemData Size: %d with reference field index: %d", field.OutputName, lenItemData, srcIndex)
+	field.log.infof(10, "converterLookup for field [%s], itemData Size: %d with reference field index: %d", field.OutputName, lenItemData, srcIndex)
 
 	if lenItemData > 0 && srcIndex < lenItemData {
-		// cross check the slice size
+		// cross check the slice size, the referenced value may be converted into a number
+		key := itemString((*itemData)[srcIndex])
 		switch mapLookup.lookupOption {
 		case LookupOptionDefault:
-			resultList = mapLookup.keyValueMap[(*itemData)[srcIndex].(string)]
+			resultList = mapLookup.keyValueMap[key]
 		case LookupOptionSubstring:
-			resultList = lookupViaSubstring((*itemData)[srcIndex].(string), mapLookup.keyValueSlice)
+			resultList = lookupViaSubstring(key, mapLookup.keyValueSlice)
 		case LookupOptionRegexp:
-			resultList = lookupViaRegexp((*itemData)[srcIndex].(string), mapLookup.keyValueSlice)
+			resultList = lookupViaRegexp(key, mapLookup.keyValueSlice)
 		}
 	} else {
 		resultList = nil
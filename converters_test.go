@@ -0,0 +1,52 @@
+package qc
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func init() {
+	RegisterConverter("Suffix", func(params []string) (Converter, error) {
+		if len(params) != 1 {
+			return nil, errors.New("suffix requires 1 parameter")
+		}
+		return func(itemData *[]interface{}, input string, field *Field) (result *string) {
+			*itemData = append(*itemData, input+params[0])
+			return nil
+		}, nil
+	})
+	// a broken converter which writes no item, thus the records don't fit the output columns
+	RegisterConverter("Drop", func(params []string) (Converter, error) {
+		return func(itemData *[]interface{}, input string, field *Field) (result *string) {
+			return nil
+		}, nil
+	})
+}
+
+func TestRegisterConverter(t *testing.T) {
+	tt.True(t, strings.Contains(strings.Join(ConverterNames(), ","), "lookup,sec2day,sec2hour,subfile,suffix,time2date"))
+
+	dir := t.TempDir()
+	config := &CSVConvertorConfig{
+		Output:    filepath.Join(dir, "output.xlsx"),
+		SheetName: "data",
+		Fields:    ParseFieldConfigs("Name,Name,20,suffix,@example.com", "Team,Team,10,suffix"),
+	}
+	// the factory error is returned with the path of the field
+	_, err := NewPipeline(config, nil)
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+	tt.Equal(t, "invalid config (path: fields.1): suffix requires 1 parameter", err.Error())
+
+	config.Fields = config.Fields[:1]
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Equal(t, ConverterTypeCustom, pipeline.fieldSlice[0].converterType)
+
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(testCSV)))
+	tt.Equal(t, []string{"alice@example.com"}, readSheet(t, config.Output, "data")[1])
+}
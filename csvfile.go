@@ -1,7 +1,7 @@
-package main
+package qc
 
 import (
-	"encoding/csv"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,25 +9,34 @@ import (
 	"strings"
 )
 
-// processCSVHeader identify the field position and update in the fieldMap, return the total of found fields
-func processCSVHeader(header []string, fieldMap map[string]*Field) int {
+// processCSVHeader identify the field position and update in the fieldMap, return the total of found fields.
+// A field matches the header names by the input name, the aliases or the regex, or the column by its position,
+// the first matching column is taken unless the field is a subfile field, which takes all the matching columns
+func (p *Pipeline) processCSVHeader(header []string, fieldMap map[string]*Field) int {
 	count := 0
-	// remove the \ufeff from the header[0] if it exist
-	if len(header) > 0 {
-		header[0] = strings.Replace(header[0], "\ufeff", "", -1)
+	// reset the positions found in a previous run
+	for _, field := range fieldMap {
+		field.inputPos = -1
+		field.inputPosArray = field.inputPosArray[:0]
 	}
+	trimBOM(header)
 
-	infof(7, "csv header list: %s", header)
+	p.log.infof(7, "csv header list: %s", header)
 
 	for id, iter := range header {
-		field := fieldMap[iter]
-		if field != nil {
-			switch field.Type {
-			case "subfile":
+		for _, field := range fieldMap {
+			if field.header == nil || !field.header.match(id, iter) {
+				continue
+			}
+			switch {
+			case field.isSubfile():
 				field.inputPosArray = append(field.inputPosArray, id)
 			default:
+				if field.inputPos >= 0 {
+					continue
+				}
 				field.inputPos = id
-				infof(6, "Position in csv file: %d for field [%s]", field.inputPos, field.InputName)
+				p.log.infof(6, "Position in csv file: %d for field [%s]", field.inputPos, field.InputName)
 			}
 			count++
 		}
@@ -35,179 +44,266 @@ func processCSVHeader(header []string, fieldMap map[string]*Field) int {
 	return count
 }
 
+// trimBOM removes the \ufeff from the header[0] if it exist
+func trimBOM(header []string) {
+	if len(header) > 0 {
+		header[0] = strings.Replace(header[0], "\ufeff", "", -1)
+	}
+}
+
+// ReadHeader reads the header line of the csv content in input, in the dialect of options
+func ReadHeader(input io.Reader, options InputOptions) ([]string, error) {
+	r, err := newCSVReader(input, "", options)
+	if err != nil {
+		return nil, err
+	}
+	header, _, err := r.Read()
+	if err != nil {
+		return nil, &Error{Kind: ErrInputRead, Row: options.SkipRows + 1, Err: err}
+	}
+	trimBOM(header)
+	return header, nil
+}
+
 // processCSVRecord takes the record content as the input, and generates the output slice based on the fieldSlice definition
-func processCSVRecord(record []string, fieldSlice []*Field, fieldsMap map[string]*Field) ([]string, error) {
+func (p *Pipeline) processCSVRecord(record []string, fieldSlice []*Field) ([]string, error) {
 	result := make([]string, 0)
-	len := len(record)
+	size := len(record)
 	for _, value := range fieldSlice {
-		switch value.converterType {
-		case ConverterTypeSubfile:
-			subFile := config.subfilesMap[value.Params[0].(string)]
-			if subFile == nil {
-				return nil, fmt.Errorf("cannot find subfile: %s in config file", value.Params[0])
+		switch {
+		case value.isSubfile():
+			// keep the result aligned with fieldSlice, the subfile field itself writes nothing
+			result = append(result, "")
+		case value.inputPos == -1:
+			result = append(result, "")
+		case value.inputPos < size:
+			// find the value at the corresponding field position
+			content := record[value.inputPos]
+			result = append(result, content)
+		default:
+			return nil, &Error{Kind: ErrInputRead, Field: value.InputName,
+				Err: fmt.Errorf("field position: %d is bigger than the actual field size: %d", value.inputPos, size)}
+		}
+	}
+	return result, nil
+}
+
+// processSubfileRecords saves the subfile records of the record, in which itemData is the converted master record
+// for the subfile fields referring to the master output fields, and accepted reports whether the master record
+// passes the main filters
+func (p *Pipeline) processSubfileRecords(record []string, itemData []interface{}, accepted bool) error {
+	size := len(record)
+	for _, value := range p.fieldSlice {
+		if !value.isSubfile() {
+			continue
+		}
+		subFile := p.subfilesMap[value.Params[0].(string)]
+		if subFile == nil {
+			return &Error{Kind: ErrConfigInvalid, Field: value.InputName, Err: fmt.Errorf("cannot find subfile: %s in config file", value.Params[0])}
+		}
+		if subFile.FollowFilter && !accepted {
+			continue
+		}
+		//process the fields in the subFile
+		for _, field := range subFile.fieldSlice {
+			if field.masterPos >= 0 {
+				field.value = ""
+				if field.masterPos < len(itemData) {
+					field.value = itemString(itemData[field.masterPos])
+				}
+				continue
+			}
+			pos := field.column
+			if masterField := p.fieldsMap[field.InputName]; masterField != nil {
+				pos = masterField.inputPos
 			}
-			//process the fields in the subFile
+			if pos >= 0 && pos < size {
+				field.value = record[pos]
+			} else {
+				field.value = ""
+			}
+		}
+		// the values of the repeated columns, in which the cells of the explode field are split into multiple values
+		values := make([]string, 0, len(value.inputPosArray))
+		for _, id := range value.inputPosArray {
+			if value.converterType != ConverterTypeExplode {
+				values = append(values, record[id])
+				continue
+			}
+			for _, item := range strings.Split(record[id], value.Params[1].(string)) {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+		}
+		for _, content := range values {
+			parts, err := subFile.splitValue(content)
+			if err != nil {
+				continue
+			}
+			// add the content to the array
+			subRecord := make([]string, 0)
 			for _, field := range subFile.fieldSlice {
-				masterField := fieldsMap[field.InputName]
-				if masterField != nil {
-					field.value = record[masterField.inputPos]
+				if isSubfileValue(field.InputName) {
+					subRecord = append(subRecord, parts[0])
+					parts = parts[1:]
 				} else {
-					field.value = ""
+					subRecord = append(subRecord, field.value)
 				}
 			}
-			for _, id := range value.inputPosArray {
-				// add the content to the array
-				subRecord := make([]string, 0)
-				var err error
-				var content string
-				for _, field := range subFile.fieldSlice {
-					if field.InputName != "value" {
-						subRecord = append(subRecord, field.value)
-					} else {
-						content, err = processSubFields(subFile.Name, record[id], field.OutputName)
-						if err == nil {
-							subRecord = append(subRecord, content)
-						}
-					}
-				}
-				// save the new record into the subFile master list
-				if err == nil {
-					subFile.records = append(subFile.records, subRecord)
-				}
+			// save the new record into the subFile output
+			saved, err := p.saveRecord(subFile.writer, subFile.Output, subFile.SheetName, subRecord, subFile.fieldSlice, subFile.filters)
+			if err != nil {
+				return err
 			}
-		default:
-			if value.inputPos == -1 {
-				result = append(result, "")
-			} else if value.inputPos < len {
-				// find the value at the corresponding field position
-				content := record[value.inputPos]
-				result = append(result, content)
-			} else {
-				return nil, fmt.Errorf("field (%s)'s position: %d is bigger than the actual field size: %d", value.InputName, value.inputPos, len)
+			if saved {
+				subFile.saveCount++
+				p.log.infof(10, "process subFile[%s], %d, record [%s]", subFile.Output, subFile.saveCount, subRecord)
 			}
 		}
 	}
-	return result, nil
+	return nil
 }
 
-func openInput(path string) (f *os.File, err error) {
+// OpenInput opens the input file at path, or returns the standard input if path is empty
+func OpenInput(path string) (f *os.File, err error) {
 	if path == "" {
 		return os.Stdin, nil
 	}
 
 	f, err = os.Open(path)
-	return
-}
-
-func ProcessCSVFile(config CSVConvertorConfig) error {
-	inf, err := openInput(config.Input)
 	if err != nil {
-		fatalf("unable to open input file: %s", err)
+		return nil, &Error{Kind: ErrInputRead, File: path, Err: err}
 	}
-	defer inf.Close()
-
-	r := csv.NewReader(inf)
-
-	xlsFile := createExcelFile(config.fieldSlice, config.Output, config.SheetName)
-
-	defer xlsFile.XLSX.Close()
+	return f, nil
+}
 
+// processCSV converts the records read from r, which reads the input file fileName, and saves them into writer
+func (p *Pipeline) processCSV(ctx context.Context, fileName string, r recordReader, writer RecordWriter) error {
 	header := true
+	headerSize := 0
+	_, dynamic := r.(dynamicHeader)
+	hasHeader := p.config.hasHeader() || dynamic // the JSON reader always returns the header
+	source := p.config.SourceFileField
 
 	recordCount := 0
 	saveCount := 0
 
 	for {
-		record, err := r.Read()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		record, row, err := r.Read()
 
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("unable to read record: %v", err)
+			return &Error{Kind: ErrInputRead, File: fileName, Row: row, Err: err}
 		}
 
 		if header {
-			count := processCSVHeader(record, config.fieldsMap)
+			header = false
+			headerSize = len(record)
+			columns := record
+			if !hasHeader {
+				// the first record is data, the columns are only referred by their positions
+				trimBOM(record)
+				columns = positionalHeader(headerSize)
+			}
+			// count the matches of the input columns only, as the source column always matches
+			count := p.processCSVHeader(columns, p.fieldsMap)
+			if source != "" {
+				columns = withSourceColumn(columns, headerSize, source)
+				p.processCSVHeader(columns, p.fieldsMap)
+			}
+			report := p.headerReport(fileName, columns)
+			p.reports = append(p.reports, report)
 			if count == 0 {
-				return fmt.Errorf("unable to found matched header fields")
+				return &Error{Kind: ErrInputRead, File: fileName, Row: row, Err: errors.New("unable to found matched header fields")}
+			}
+			if err := p.checkHeader(report, row); err != nil {
+				return err
 			}
-			header = false
 
-			continue
+			if hasHeader {
+				continue
+			}
+		} else if h, ok := r.(dynamicHeader); ok {
+			if fields, changed := h.header(); changed {
+				headerSize = len(fields)
+				if source != "" {
+					fields = withSourceColumn(fields, headerSize, source)
+				}
+				p.processCSVHeader(fields, p.fieldsMap)
+			}
+		}
+		if source != "" {
+			record = withSourceColumn(record, headerSize, fileName)
 		}
 
-		result, err := processCSVRecord(record, config.fieldSlice, config.fieldsMap)
+		result, err := p.processCSVRecord(record, p.fieldSlice)
+		saved := false
+		if err == nil {
+			// the subfile records are derived after the master conversion, to refer to the converted master fields
+			itemData := p.convertRecord(result, p.fieldSlice)
+			accepted := filterRecord(itemData, p.fieldSlice, p.filters)
+			err = p.processSubfileRecords(record, itemData, accepted)
+			if err == nil && accepted {
+				err = p.writeRecord(writer, p.config.Output, p.config.SheetName, itemData)
+				saved = err == nil
+			}
+		}
 		if err != nil {
-			return fmt.Errorf("unable to convertFields: %v", err)
+			var recordErr *Error
+			if errors.As(err, &recordErr) {
+				if recordErr.Kind == ErrOutputWrite {
+					// the error holds the output file, add the input record the failed row is converted from
+					if fileName != "" {
+						recordErr.Err = fmt.Errorf("input file %s row %d: %w", fileName, row, recordErr.Err)
+					} else {
+						recordErr.Err = fmt.Errorf("input row %d: %w", row, recordErr.Err)
+					}
+				} else {
+					recordErr.File = fileName
+					recordErr.Row = row
+				}
+			}
+			return err
 		}
 
 		recordCount++
 		if recordCount%1000 == 0 {
-			infof(3, "processed csv records: %d", recordCount)
+			p.log.infof(3, "processed csv records: %d", recordCount)
 		}
 
-		if res := saveRecordInExcelFile(xlsFile, config.SheetName, result, config.fieldSlice, false); res {
+		if saved {
 			saveCount++
 		}
+		p.resetNestedRows()
 	}
 
-	infof(3, "process main output file: %s, total records processed: %d, total records saved: %d",
-		config.Output, recordCount, saveCount)
-
-	if err := xlsFile.XLSX.SaveAs(config.Output); err != nil {
-		fatalf("save xlsx file (%s) failed, err: %s", config.Output, err)
-		return err
-	}
+	p.log.infof(3, "process input file: %s, main output file: %s, total records processed: %d, total records saved: %d",
+		fileName, p.config.Output, recordCount, saveCount)
 
 	return nil
 }
 
-func processSubFiles(subFile *SubFile) error {
-	xlsSubFile := createExcelFile(subFile.fieldSlice, subFile.Output, subFile.SheetName)
-	defer xlsSubFile.XLSX.Close()
-
-	for id, record := range subFile.records {
-		// process the subFile
-		saveRecordInExcelFile(xlsSubFile, subFile.SheetName, record, subFile.fieldSlice, true)
-		infof(10, "process subFile[%s], %d, record [%s]", subFile.Output, id, record)
+// withSourceColumn returns the record with the value appended as the column next to the size columns of the header,
+// the short record is filled up with empty values
+func withSourceColumn(record []string, size int, value string) []string {
+	for len(record) < size {
+		record = append(record, "")
 	}
-
-	if err := xlsSubFile.XLSX.SaveAs(subFile.Output); err != nil {
-		fatalf("save xlsx file (%s) failed, err: %s", subFile.Output, err)
-		return err
-	}
-
-	infof(3, "process subFile: %s, sheet: %s, total records saved: %d", subFile.Output, subFile.SheetName, len(subFile.records))
-	return nil
+	return append(record[:size:size], value)
 }
 
-// this is only to process the subField, for jira only
-func processSubFields(subFileName, record, outputName string) (string, error) {
-	if record == "" {
-		return "", errors.New("empty record value")
-	}
-
-	if subFileName != "JiraLogTime" {
-		return record, nil
-	}
-
-	// the Jira "Log Work" field format: ";date;user id;total seconds"
-	fields := strings.Split(record, ";")
-	length := len(fields)
-
-	if length < 4 {
-		return "", errors.New("invalid record format, must have 4 sections")
-	}
-
-	switch outputName {
-	case "Hours":
-		return fields[length-1], nil
-	case "Reporter":
-		return fields[length-2], nil
-	case "Date":
-		return fields[length-3], nil
+// resetNestedRows drops the nested subfile rows of the record, which remain if the record is filtered out
+func (p *Pipeline) resetNestedRows() {
+	for _, subFile := range p.subfiles {
+		if rows, ok := subFile.writer.(*nestedRows); ok {
+			rows.reset()
+		}
 	}
-
-	return "", errors.New("unsupported output name")
 }
@@ -0,0 +1,54 @@
+package qc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// inputEncodings maps the supported names of the encoding setting to the text encodings
+var inputEncodings = map[string]encoding.Encoding{
+	"utf-8":        unicode.UTF8,
+	"utf8":         unicode.UTF8,
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"gbk":          simplifiedchinese.GBK,
+	"gb18030":      simplifiedchinese.GB18030,
+	"shift-jis":    japanese.ShiftJIS,
+	"shift_jis":    japanese.ShiftJIS,
+	"sjis":         japanese.ShiftJIS,
+	"windows-1252": charmap.Windows1252,
+	"cp1252":       charmap.Windows1252,
+	"latin-1":      charmap.ISO8859_1,
+	"latin1":       charmap.ISO8859_1,
+	"iso-8859-1":   charmap.ISO8859_1,
+}
+
+// DecodeInput returns the input decoded from the named encoding into UTF-8. A UTF-8 or UTF-16 byte order mark
+// at the start of the input overrides the encoding, thus the UTF-16 input is detected if the encoding is empty
+func DecodeInput(input io.Reader, name string) (io.Reader, error) {
+	fallback, err := inputEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	return transform.NewReader(input, unicode.BOMOverride(fallback.NewDecoder())), nil
+}
+
+// inputEncoding returns the encoding of the name, UTF-8 if the name is empty
+func inputEncoding(name string) (encoding.Encoding, error) {
+	if name == "" {
+		return unicode.UTF8, nil
+	}
+	enc, ok := inputEncodings[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %s, should be utf-8, utf-16le, utf-16be, gbk, gb18030, shift-jis, windows-1252 or latin-1", name)
+	}
+	return enc, nil
+}
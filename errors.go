@@ -0,0 +1,59 @@
+package qc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// the kinds of failures returned by the conversion stages, use errors.Is to check the kind of a returned error
+var (
+	ErrConfigInvalid = errors.New("invalid config")
+	ErrInputRead     = errors.New("unable to read input")
+	ErrLookupLoad    = errors.New("unable to load lookup")
+	ErrOutputWrite   = errors.New("unable to write output")
+)
+
+// Error wraps the failure of a conversion stage with the file, row and field context
+type Error struct {
+	Kind  error  // one of the Err* values above
+	File  string // the config, input, lookup or output file name
+	Row   int    // the row number in the file starting from 1, 0 if not applicable
+	Field string // the field name, empty if not applicable
+	Path  string // the YAML path of the setting in the config file, e.g. fields.3, empty if not applicable
+	Err   error
+}
+
+func (e *Error) Error() string {
+	context := make([]string, 0, 4)
+	if e.File != "" {
+		context = append(context, "file: "+e.File)
+	}
+	if e.Row > 0 {
+		context = append(context, fmt.Sprintf("row: %d", e.Row))
+	}
+	if e.Field != "" {
+		context = append(context, "field: "+e.Field)
+	}
+	if e.Path != "" {
+		context = append(context, "path: "+e.Path)
+	}
+
+	msg := e.Kind.Error()
+	if len(context) > 0 {
+		msg += " (" + strings.Join(context, ", ") + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is of the target kind
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
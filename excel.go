@@ -1,4 +1,4 @@
-package main
+package qc
 
 import (
 	"fmt"
@@ -11,14 +11,27 @@ import (
 )
 
 const defaultHeadingRow = 1
+
+// maxSheetNameLength is the maximum length of the sheet names allowed by Excel
+const maxSheetNameLength = 31
 const defaultHeadingStyle = `{"font": {"bold": true}, "alignment":{"horizontal":"center","ident":1,"justify_last_line":true,"reading_order":0,"relative_indent":1,"shrink_to_fit":true,"vertical":"middle","wrap_text":true}}`
 
-// File represents a single-sheet xlsx file
+// File represents a single sheet in a xlsx file, the rows are written through a stream writer
+// thus the memory usage doesn't grow with the number of rows.
+// When the sheet is full, the rows are continued in the sheets <SheetName>_2, <SheetName>_3, and so on.
 type File struct {
 	SheetName string
 	Columns   []column
 	NextRow   int
 	XLSX      *excelize.File
+
+	sheetCount   int    // the number of sheets used, including the rollover sheets
+	currentSheet string // the name of the sheet being written
+	maxRows      int    // the maximum rows in a sheet including the heading row
+	stream       *excelize.StreamWriter
+	log          *Logger
+	headingStyle string
+	headingSaved bool // the heading row is written when the first row is added or the sheet is flushed
 }
 
 type column struct {
@@ -39,71 +52,138 @@ func IsFile(path string) bool {
 	return err == nil && info.Mode().IsRegular()
 }
 
-// NewExcel returns a pointer to an excel.File with all columns initialised to defaults
-func NewExcel(fileName string, sheetName string, colNames []string, width []int, funcCell []bool) *File {
-	f := new(File)
-	f.SheetName = sheetName
-	f.NextRow = defaultHeadingRow
-	var err error
-
+// OpenWorkbook opens the xlsx file at fileName, or creates a new workbook if the file doesn't exist,
+// in which case created is true and the workbook includes the default sheet "Sheet1".
+func OpenWorkbook(fileName string, log *Logger) (xlsx *excelize.File, created bool, err error) {
 	if IsFile(fileName) {
-		f.XLSX, err = excelize.OpenFile(fileName)
+		xlsx, err = excelize.OpenFile(fileName)
 		if err != nil {
-			errorf("Output file already exist, but return error when open: %s", err)
-			os.Exit(0)
-		}
-		infof(2, "excelize.OpenFile open file: %s for sheet [%s]", fileName, sheetName)
-		// for existing file, make sure to delete the old sheet first
-		sheetID := f.XLSX.GetSheetIndex(sheetName)
-		if sheetID == 0 {
-			infof(2, "sheet [%s] doesn't exist in the current file, create a new sheet", sheetName)
-		} else {
-			infof(2, "sheet [%s] exist in the current file %s, removed", sheetName, fileName)
-			f.XLSX.DeleteSheet(sheetName)
-			if err := f.XLSX.SaveAs(config.Output); err != nil {
-				errorf("save spreadsheet first after deleting the old sheet, err: %s", err)
-			}
-		}
-	} else {
-		infof(2, "Output file doesn't exist, creating a new file at %s", fileName)
-		//for new file, then create new sheet
-		f.XLSX = excelize.NewFile()
-		// delete the default sheet
-		f.XLSX.DeleteSheet("Sheet1")
-		if err := f.XLSX.SaveAs(config.Output); err != nil {
-			errorf("save spreadsheet first by deleting the default sheet: sheet1, err: %s", err)
+			return nil, false, &Error{Kind: ErrOutputWrite, File: fileName, Err: fmt.Errorf("output file already exist, but return error when open: %w", err)}
 		}
+		log.infof(2, "excelize.OpenFile open file: %s", fileName)
+		return xlsx, false, nil
 	}
 
-	sheetID := f.XLSX.NewSheet(sheetName)
-	f.XLSX.SetActiveSheet(sheetID)
+	log.infof(2, "Output file doesn't exist, creating a new file at %s", fileName)
+	return excelize.NewFile(), true, nil
+}
+
+// NewExcel adds the sheet into the workbook and returns a pointer to an excel.File with all columns initialised to defaults.
+// If the sheet already exists in the workbook, its content is replaced. Nothing is written to the file until the workbook is saved.
+func NewExcel(xlsx *excelize.File, sheetName string, colNames []string, width []int, funcCell []bool, log *Logger) (*File, error) {
+	f := new(File)
+	f.SheetName = sheetName
+	f.XLSX = xlsx
+	f.maxRows = excelize.TotalRows
+	f.log = log
+
+	// remove the rollover sheets of the previous runs
+	for _, iter := range f.XLSX.GetSheetList() {
+		if isRolloverSheet(iter, sheetName) {
+			log.infof(2, "rollover sheet [%s] exist in the current file, removed", iter)
+			f.XLSX.DeleteSheet(iter)
+		}
+	}
 
 	xc := columnRefs(len(colNames))
 	for i := range colNames {
 		c := column{
 			Ref:         xc[i],
-			HeadingCell: xc[i] + strconv.Itoa(f.NextRow), // "A1", "A2" etc
+			HeadingCell: xc[i] + strconv.Itoa(defaultHeadingRow), // "A1", "A2" etc
 			Heading:     colNames[i],
 			Width:       width[i],
 			FuncCell:    funcCell[i],
 		}
 		f.Columns = append(f.Columns, c)
-		f.XLSX.SetCellValue(f.SheetName, c.HeadingCell, c.Heading)
-		f.XLSX.SetColWidth(f.SheetName, c.Ref, c.Ref, float64(c.Width))
+	}
+
+	if err := f.newSheet(sheetName); err != nil {
+		return nil, err
 	}
 
 	f.SetHeadingStyle(defaultHeadingStyle)
 
-	return f
+	return f, nil
+}
+
+// newSheet starts writing the rows into the sheet, replacing its content if it already exists
+func (f *File) newSheet(sheetName string) error {
+	if f.XLSX.GetSheetIndex(sheetName) == -1 {
+		f.log.infof(2, "sheet [%s] doesn't exist in the current file, create a new sheet", sheetName)
+	} else {
+		f.log.infof(2, "sheet [%s] exist in the current file, removed", sheetName)
+		f.XLSX.DeleteSheet(sheetName)
+	}
+
+	sheetID := f.XLSX.NewSheet(sheetName)
+	f.XLSX.SetActiveSheet(sheetID)
+
+	var err error
+	if f.stream, err = f.XLSX.NewStreamWriter(sheetName); err != nil {
+		return err
+	}
+	for i, c := range f.Columns {
+		// the column width must be set before any row is written
+		if err := f.stream.SetColWidth(i+1, i+1, float64(c.Width)); err != nil {
+			return fmt.Errorf("column %s: %w", c.Heading, err)
+		}
+	}
+
+	f.sheetCount++
+	f.currentSheet = sheetName
+	f.NextRow = defaultHeadingRow
+	f.headingSaved = false
+	return nil
+}
+
+// rollover ends the current sheet and continues the rows in the next rollover sheet
+func (f *File) rollover() error {
+	if err := f.stream.Flush(); err != nil {
+		return err
+	}
+	sheetName := rolloverSheetName(f.SheetName, f.sheetCount+1)
+	f.log.infof(3, "sheet [%s] reaches %d rows, continue in sheet [%s]", f.currentSheet, f.maxRows, sheetName)
+	return f.newSheet(sheetName)
+}
+
+// rolloverSheetName returns the name of the nth sheet, e.g. "rawdata_2", the name is truncated to fit the suffix
+func rolloverSheetName(sheetName string, n int) string {
+	suffix := "_" + strconv.Itoa(n)
+	runes := []rune(sheetName)
+	if len(runes)+len(suffix) > maxSheetNameLength {
+		runes = runes[:maxSheetNameLength-len(suffix)]
+	}
+	return string(runes) + suffix
+}
+
+// isRolloverSheet reports whether name is one of the rollover sheets of sheetName
+func isRolloverSheet(name string, sheetName string) bool {
+	pos := strings.LastIndex(name, "_")
+	if pos == -1 {
+		return false
+	}
+	n, err := strconv.Atoi(name[pos+1:])
+	return err == nil && n >= 2 && strings.EqualFold(name, rolloverSheetName(sheetName, n))
 }
 
-// SetHeadingStyle sets the column heading style
+// SetHeadingStyle sets the column heading style, it must be called before any row is added
 func (f *File) SetHeadingStyle(style string) {
-	startCell := f.Columns[0].HeadingCell
-	endCell := f.Columns[len(f.Columns)-1].HeadingCell
+	f.headingStyle = style
+}
+
+// saveHeading writes the heading row with the heading style
+func (f *File) saveHeading() error {
+	f.headingSaved = true
+	if len(f.Columns) == 0 {
+		return nil
+	}
 
-	st, _ := f.XLSX.NewStyle(style)
-	f.XLSX.SetCellStyle(f.SheetName, startCell, endCell, st)
+	st, _ := f.XLSX.NewStyle(f.headingStyle)
+	row := make([]interface{}, 0, len(f.Columns))
+	for _, c := range f.Columns {
+		row = append(row, excelize.Cell{StyleID: st, Value: c.Heading})
+	}
+	return f.stream.SetRow(f.Columns[0].HeadingCell, row)
 }
 
 // AddRow adds a row of data to the sheet
@@ -113,19 +193,44 @@ func (f *File) AddRow(sheetName string, data []interface{}) error {
 		return fmt.Errorf("number of data items (%d) does not equal the number of columns (%d)", len(data), len(f.Columns))
 	}
 
+	if !f.headingSaved {
+		if err := f.saveHeading(); err != nil {
+			return err
+		}
+	}
+
+	if f.NextRow >= f.maxRows {
+		if err := f.rollover(); err != nil {
+			return err
+		}
+		if err := f.saveHeading(); err != nil {
+			return err
+		}
+	}
+
 	f.NextRow++
+	row := make([]interface{}, 0, len(data))
 	for i, c := range f.Columns {
-		cell := c.Ref + strconv.Itoa(f.NextRow) // eg "A1", "A2"... "AA26"
 		if c.FuncCell {
 			// replace the row name with actual row value
 			value := strings.Replace(data[i].(string), "{row}", strconv.Itoa(f.NextRow), -1)
-			f.XLSX.SetCellFormula(sheetName, cell, value)
+			row = append(row, excelize.Cell{Formula: value})
 		} else {
-			f.XLSX.SetCellValue(sheetName, cell, data[i])
+			row = append(row, data[i])
 		}
 	}
 
-	return nil
+	return f.stream.SetRow("A"+strconv.Itoa(f.NextRow), row)
+}
+
+// Flush ends writing the rows, it must be called before the workbook is saved
+func (f *File) Flush() error {
+	if !f.headingSaved {
+		if err := f.saveHeading(); err != nil {
+			return err
+		}
+	}
+	return f.stream.Flush()
 }
 
 // columnRefs generates the specified number of column references - eg "A", "B" ... "Z", "AA", "AB" etc.
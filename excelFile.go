@@ -1,6 +1,7 @@
-package main
+package qc
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -8,19 +9,18 @@ import (
 )
 
 // loadLookupExcelFile initializes and loads the mapping from configured file for the lookup function
-func loadLookupExcelFile(lookup *Lookup) error {
+func loadLookupExcelFile(lookup *Lookup, log *Logger) error {
 	var err error
 
 	xlFile, err := excelize.OpenFile(lookup.FileName)
 	if err != nil {
-		errorf("loadExcelFile return err: %s when processing file %s", err, lookup.FileName)
-		return err
+		return &Error{Kind: ErrLookupLoad, File: lookup.FileName, Err: err}
 	}
+	defer xlFile.Close()
 
 	rows, err := xlFile.GetRows(lookup.SheetName)
 	if err != nil {
-		errorf("GetRows return err: %s when processing sheet %s", err, lookup.SheetName)
-		return err
+		return &Error{Kind: ErrLookupLoad, File: lookup.FileName, Err: fmt.Errorf("sheet %s: %w", lookup.SheetName, err)}
 	}
 
 	// skip the first row as it is for header
@@ -41,15 +41,17 @@ func loadLookupExcelFile(lookup *Lookup) error {
 
 				lookupRegex.Value = content
 				if lookup.lookupOption == LookupOptionRegexp {
-					lookupRegex.Regexp = regexp.MustCompile(key)
+					if lookupRegex.Regexp, err = regexp.Compile(key); err != nil {
+						return &Error{Kind: ErrLookupLoad, File: lookup.FileName, Row: i + 1, Err: fmt.Errorf("sheet %s: %w", lookup.SheetName, err)}
+					}
 				}
 				lookup.keyValueSlice = append(lookup.keyValueSlice, lookupRegex)
 			} else {
 				existing := lookup.keyValueMap[key]
 				// if need to display warning
-				if *warning && existing != nil {
-					log.Warn("lookup file (", lookup.FileName, ") sheet (", lookup.SheetName, ") has duplicated key ", key, " with value ",
-						existing, ", will be overrided by new value ", content)
+				if existing != nil {
+					log.warnf("lookup file (%s) sheet (%s) has duplicated key %s with value %s, will be overrided by new value %s",
+						lookup.FileName, lookup.SheetName, key, existing, content)
 				}
 				lookup.keyValueMap[key] = content
 			}
@@ -59,32 +61,21 @@ func loadLookupExcelFile(lookup *Lookup) error {
 	return nil
 }
 
-func createExcelFile(fieldSlice []*Field, fileName string, sheetName string) (xlsFile *File) {
-	header := []string{}
-	width := []int{}
-	funcCelll := []bool{}
-
-	for _, iter := range fieldSlice {
-		if iter.OutputName != "" {
-			// if the output name is not defined, then the field won't be output
-			width = append(width, iter.Width)
-			header = append(header, iter.OutputName)
-			if iter.converterType == ConverterTypeFunc {
-				funcCelll = append(funcCelll, true)
-			} else {
-				funcCelll = append(funcCelll, false)
-			}
-		}
+// the fieldSlice is the description of each field in the record list, the record is not saved if it is
+// filtered out by filters. return true if the record is saved into writer of the output file fileName.
+func (p *Pipeline) saveRecord(writer RecordWriter, fileName string, sheetName string, record []string, fieldSlice []*Field, filters []*Filter) (bool, error) {
+	itemData := p.convertRecord(record, fieldSlice)
+	if !filterRecord(itemData, fieldSlice, filters) {
+		return false, nil
 	}
-
-	xlFile := NewExcel(fileName, sheetName, header, width, funcCelll)
-
-	return xlFile
+	if err := p.writeRecord(writer, fileName, sheetName, itemData); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// the fieldSlice is the description of each field in the record list
-// return true if the save result is successful.
-func saveRecordInExcelFile(xlsFile *File, sheetName string, record []string, fieldSlice []*Field, isSubfile bool) bool {
+// convertRecord converts the record into the output items by the converters of the fields in fieldSlice
+func (p *Pipeline) convertRecord(record []string, fieldSlice []*Field) []interface{} {
 	itemData := make([]interface{}, 0)
 	var res *string
 	for id, iter := range record {
@@ -96,15 +87,17 @@ func saveRecordInExcelFile(xlsFile *File, sheetName string, record []string, fie
 			}
 		}
 	}
+	return itemData
+}
 
-	// the filter function is not applicable to subfile data
-	if isSubfile || filterRecord(itemData, config.fieldSlice, config.Filters) {
-		err := xlsFile.AddRow(sheetName, itemData)
-		if err != nil {
-			errorf("AddRow return error: %s for items: %s", err, itemData)
-			return false
-		}
-		return true
+// writeRecord writes the converted items into writer of the output file fileName
+func (p *Pipeline) writeRecord(writer RecordWriter, fileName string, sheetName string, itemData []interface{}) error {
+	err := writer.AddRow(sheetName, itemData)
+	if err == nil {
+		return nil
+	}
+	if sheetName != "" {
+		err = fmt.Errorf("sheet %s: %w", sheetName, err)
 	}
-	return false
+	return &Error{Kind: ErrOutputWrite, File: fileName, Err: err}
 }
@@ -0,0 +1,55 @@
+package qc
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestAddRowRollover(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.xlsx")
+	xlsx := excelize.NewFile()
+	// the rollover sheets of a previous run are removed
+	xlsx.NewSheet("data_3")
+
+	f, err := NewExcel(xlsx, "data", []string{"ID", "Double"}, []int{10, 12}, []bool{false, true}, nil)
+	tt.Nil(t, err)
+	f.maxRows = 3
+	for i := 1; i <= 5; i++ {
+		tt.Nil(t, f.AddRow("data", []interface{}{i, "A{row}*2"}))
+	}
+	tt.Nil(t, f.Flush())
+	xlsx.DeleteSheet("Sheet1")
+	tt.Nil(t, xlsx.SaveAs(output))
+
+	result, err := excelize.OpenFile(output)
+	tt.Nil(t, err)
+	defer result.Close()
+
+	tt.Equal(t, []string{"data", "data_2", "data_3"}, result.GetSheetList())
+	expected := [][]string{{"1", "2"}, {"3", "4"}, {"5"}}
+	for id, sheet := range result.GetSheetList() {
+		rows, _ := result.GetRows(sheet)
+		tt.Equal(t, len(expected[id])+1, len(rows))
+		tt.Equal(t, []string{"ID", "Double"}, rows[0])
+		for row, value := range expected[id] {
+			tt.Equal(t, value, rows[row+1][0])
+			// the {row} is replaced with the row number in the current sheet
+			formula, _ := result.GetCellFormula(sheet, "B"+strconv.Itoa(row+2))
+			tt.Equal(t, "A"+strconv.Itoa(row+2)+"*2", formula)
+		}
+		width, _ := result.GetColWidth(sheet, "B")
+		tt.Equal(t, float64(12), width)
+	}
+}
+
+func TestRolloverSheetName(t *testing.T) {
+	tt.Equal(t, "rawdata_2", rolloverSheetName("rawdata", 2))
+	tt.Equal(t, "Vulnerabilities by severity _12", rolloverSheetName("Vulnerabilities by severity level", 12))
+	tt.True(t, isRolloverSheet("Rawdata_3", "rawdata"))
+	tt.False(t, isRolloverSheet("rawdata_1", "rawdata"))
+	tt.False(t, isRolloverSheet("raw_data", "raw"))
+}
@@ -0,0 +1,209 @@
+package qc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-ucfg"
+)
+
+const defaultFieldWidth = 20
+
+// FieldConfig is a field definition in the config file, in either of the below forms:
+//   - the comma-joined string "Input field name, Output field name, Cell Width, Transformation Type, Transform Parameters 1, ..."
+//   - the map with the keys input, output, width, type and params, in which params is either a list of
+//     the transform parameters or a map of the named parameters, e.g. ref, lookup, column for lookup.
+//     The optional aliases and regex keys define the other header names matching the input field,
+//     and the required key tells the input field must be found in the header
+type FieldConfig struct {
+	Input    string
+	Output   string
+	Width    int
+	Type     string
+	Params   []string
+	Aliases  []string // the other header names of the input field
+	Regex    string   // the regular expression matching the header names of the input field
+	Required bool     // the input field must be found in the header
+
+	definition string // the definition in the config file, used in the error messages
+	err        error  // the format error of the definition, reported when the field is processed
+}
+
+// fieldMapConfig is the map form of FieldConfig
+type fieldMapConfig struct {
+	Input    string      `config:"input"`
+	Output   string      `config:"output"`
+	Width    *int        `config:"width"`
+	Type     string      `config:"type"`
+	Params   interface{} `config:"params"`
+	Aliases  []string    `config:"aliases"`
+	Regex    string      `config:"regex"`
+	Required bool        `config:"required"`
+}
+
+// namedFieldParams are the names of the transform parameters in the map form, in the order of the string form
+var namedFieldParams = map[ConverterType][]string{
+	ConverterTypeSubfile:        {"name"},
+	ConverterTypeExplode:        {"name", "separator"},
+	ConverterTypeFunc:           {"formula"},
+	ConverterTypeLookup:         {"ref", "lookup", "column"},
+	ConverterTypeConstantString: {"value"},
+}
+
+// optionalFieldParams are the named parameters which can be left out to take their default values,
+// they are always the last ones of the parameters of their types
+var optionalFieldParams = []string{"separator"}
+
+// ParseFieldConfig parses the comma-joined string form of a field definition
+func ParseFieldConfig(definition string) *FieldConfig {
+	fc := &FieldConfig{definition: definition, Width: defaultFieldWidth}
+
+	fields := strings.Split(definition, ",")
+	size := len(fields)
+	if size < 2 {
+		fc.err = errors.New("must have at least 2 parameters separated by `,`")
+		return fc
+	}
+
+	fc.Input = strings.TrimSpace(fields[0])
+	fc.Output = strings.TrimSpace(fields[1])
+	if size >= 3 {
+		fc.Width, _ = strconv.Atoi(strings.TrimSpace(fields[2]))
+	}
+	if size >= 4 {
+		fc.Type = strings.TrimSpace(fields[3])
+	}
+	if size >= 5 {
+		// keep the parameters untouched as the func formula may include `,` and spaces
+		fc.Params = fields[4:]
+	}
+	return fc
+}
+
+// ParseFieldConfigs parses the string form of a list of field definitions
+func ParseFieldConfigs(definitions ...string) []*FieldConfig {
+	result := make([]*FieldConfig, 0, len(definitions))
+	for _, iter := range definitions {
+		result = append(result, ParseFieldConfig(iter))
+	}
+	return result
+}
+
+// Unpack implements ucfg.Unpacker to accept both the string and the map form
+func (fc *FieldConfig) Unpack(in interface{}) error {
+	switch value := in.(type) {
+	case string:
+		*fc = *ParseFieldConfig(value)
+		return nil
+	case map[string]interface{}:
+		return fc.unpackMap(value)
+	}
+	return fmt.Errorf("field definition must be a string or a map, got: %v", in)
+}
+
+func (fc *FieldConfig) unpackMap(in map[string]interface{}) error {
+	cfg, err := ucfg.NewFrom(in)
+	if err != nil {
+		return err
+	}
+	mc := fieldMapConfig{}
+	if err := cfg.Unpack(&mc); err != nil {
+		return err
+	}
+
+	*fc = FieldConfig{
+		Input:      strings.TrimSpace(mc.Input),
+		Output:     strings.TrimSpace(mc.Output),
+		Width:      defaultFieldWidth,
+		Type:       strings.TrimSpace(mc.Type),
+		Aliases:    mc.Aliases,
+		Regex:      mc.Regex,
+		Required:   mc.Required,
+		definition: fmt.Sprintf("%v", in),
+	}
+	if mc.Width != nil {
+		fc.Width = *mc.Width
+	}
+
+	switch params := mc.Params.(type) {
+	case nil:
+	case []interface{}:
+		for _, iter := range params {
+			fc.Params = append(fc.Params, fmt.Sprint(iter))
+		}
+	case map[string]interface{}:
+		fc.Params, fc.err = namedParams(fc.Type, params)
+	default:
+		fc.Params = []string{fmt.Sprint(params)}
+	}
+	return nil
+}
+
+// namedParams converts the named parameters of the map form into the positional parameters
+func namedParams(fieldType string, params map[string]interface{}) ([]string, error) {
+	ft, _ := FieldTypeConvert(fieldType)
+	names := namedFieldParams[ft]
+	if names == nil {
+		return nil, fmt.Errorf("type %s doesn't support named params, use a list instead", fieldType)
+	}
+
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		value, ok := params[name]
+		if !ok {
+			if containsString(optionalFieldParams, name) {
+				break
+			}
+			return nil, fmt.Errorf("missing param %s for type %s", name, fieldType)
+		}
+		result = append(result, fmt.Sprint(value))
+	}
+	unknown := make([]string, 0)
+	for name := range params {
+		if !containsString(names, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown params %s for type %s", strings.Join(unknown, ","), fieldType)
+	}
+	return result, nil
+}
+
+// checkFieldConfigs returns the error of the first field definition which fails to parse or has an invalid
+// header regex, path is the YAML path of the field list
+func checkFieldConfigs(path string, fieldConfigs []*FieldConfig) error {
+	for id, iter := range fieldConfigs {
+		if iter.err != nil {
+			return &Error{Kind: ErrConfigInvalid, Path: fmt.Sprintf("%s.%d", path, id),
+				Err: fmt.Errorf("incorrect field format %s: %w", iter.definition, iter.err)}
+		}
+		if _, err := newHeaderMatcher(iter, ""); err != nil {
+			return &Error{Kind: ErrConfigInvalid, Path: fmt.Sprintf("%s.%d", path, id), Err: err}
+		}
+	}
+	return nil
+}
+
+// hasInputField reports whether a valid field definition in fieldConfigs reads the input field name
+func hasInputField(fieldConfigs []*FieldConfig, name string) bool {
+	for _, iter := range fieldConfigs {
+		if iter.err == nil && iter.Input == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(slice []string, value string) bool {
+	for _, iter := range slice {
+		if iter == value {
+			return true
+		}
+	}
+	return false
+}
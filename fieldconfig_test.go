@@ -0,0 +1,86 @@
+package qc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+const testFieldsConfig = `
+fields:
+    - "ID, ID, 10, int"
+    - input: "Custom field (Team, Area)"
+      output: Team
+    - input: Endpoint
+      output: Server Type
+      width: 15
+      type: lookup
+      params:
+        ref: EndpointRef
+        lookup: ServerType
+        column: 2
+    - output: Note
+      type: constant
+      params:
+        value: "a, b"
+    - output: Month
+      type: func
+      params: ['IF(D{row}="", "", TEXT(D{row},"yyyy-mm"))']
+    - input: Components
+      type: lookup
+      params:
+        ref: ID
+        column: 2
+    - input: Fix Version/s
+      output: Version
+      aliases: [Fix versions]
+      regex: '^Fix Version'
+      required: true
+    - input: CVE
+      type: explode
+      params:
+        name: cves
+    - input: Labels
+      type: explode
+      params:
+        name: labels
+        separator: ","
+`
+
+func TestFieldConfigUnpack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	tt.Nil(t, os.WriteFile(path, []byte(testFieldsConfig), 0644))
+
+	config, err := ReadConfig(path)
+	tt.Nil(t, err)
+	tt.Equal(t, 9, len(config.Fields))
+
+	tt.Equal(t, &FieldConfig{Input: "ID", Output: "ID", Width: 10, Type: "int", definition: "ID, ID, 10, int"}, config.Fields[0])
+
+	team := config.Fields[1]
+	tt.Equal(t, "Custom field (Team, Area)", team.Input)
+	tt.Equal(t, "Team", team.Output)
+	tt.Equal(t, defaultFieldWidth, team.Width)
+
+	serverType := config.Fields[2]
+	tt.Equal(t, 15, serverType.Width)
+	tt.Equal(t, []string{"EndpointRef", "ServerType", "2"}, serverType.Params)
+
+	tt.Equal(t, []string{"a, b"}, config.Fields[3].Params)
+	tt.Equal(t, []string{`IF(D{row}="", "", TEXT(D{row},"yyyy-mm"))`}, config.Fields[4].Params)
+
+	tt.NotNil(t, config.Fields[5].err)
+	tt.Equal(t, "missing param lookup for type lookup", config.Fields[5].err.Error())
+
+	version := config.Fields[6]
+	tt.Equal(t, []string{"Fix versions"}, version.Aliases)
+	tt.Equal(t, "^Fix Version", version.Regex)
+	tt.True(t, version.Required)
+
+	// the separator of the explode field is optional
+	tt.Nil(t, config.Fields[7].err)
+	tt.Equal(t, []string{"cves"}, config.Fields[7].Params)
+	tt.Equal(t, []string{"labels", ","}, config.Fields[8].Params)
+}
@@ -1,4 +1,4 @@
-package main
+package qc
 
 func filterRecord(record []interface{}, fieldSlice []*Field, filters []*Filter) bool {
 
@@ -9,8 +9,8 @@ func filterRecord(record []interface{}, fieldSlice []*Field, filters []*Filter)
 
 	recordLen := len(record)
 	for _, iter := range filters {
-		if iter.fieldPos > 0 && iter.fieldPos < recordLen {
-			field := record[iter.fieldPos].(string)
+		if iter.fieldPos >= 0 && iter.fieldPos < recordLen {
+			field := itemString(record[iter.fieldPos])
 			val := iter.valueMap[field]
 			if val == 1 {
 				return true
@@ -0,0 +1,159 @@
+package qc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// the values of the headerMatch setting, which decides how the header names are compared with the input field names
+const (
+	HeaderMatchExact           = "exact"           // the names must be identical
+	HeaderMatchTrimmed         = "trimmed"         // the leading and trailing white space is ignored
+	HeaderMatchCaseInsensitive = "caseInsensitive" // the white space is trimmed and the case is ignored
+)
+
+func checkHeaderMatch(headerMatch string) error {
+	switch headerMatch {
+	case "", HeaderMatchExact, HeaderMatchTrimmed, HeaderMatchCaseInsensitive:
+		return nil
+	}
+	return fmt.Errorf("unknown headerMatch option %s, should be %s, %s or %s",
+		headerMatch, HeaderMatchExact, HeaderMatchTrimmed, HeaderMatchCaseInsensitive)
+}
+
+// headerMatcher tells whether a header name is the input field, by the input name, the aliases or the regex,
+// or whether the column is the input field referred by its position
+type headerMatcher struct {
+	mode   string
+	names  []string // the input name and the aliases, normalized by the mode
+	regex  *regexp.Regexp
+	column int // the column of the positional input name, -1 if the input is referred by name
+}
+
+// inputColumn returns the 0-based column of the positional input name, which is either the 1-based position
+// following #, e.g. #3, or the Excel column letters following $, e.g. $C, or -1 if the name is not positional
+func inputColumn(name string) int {
+	if len(name) < 2 {
+		return -1
+	}
+	switch name[0] {
+	case '#':
+		if pos, err := strconv.Atoi(name[1:]); err == nil && pos >= 1 {
+			return pos - 1
+		}
+	case '$':
+		if pos, err := excelize.ColumnNameToNumber(name[1:]); err == nil {
+			return pos - 1
+		}
+	}
+	return -1
+}
+
+// positionalHeader returns the header of the input without the header row, which is made of the positional names
+func positionalHeader(size int) []string {
+	header := make([]string, 0, size)
+	for i := 1; i <= size; i++ {
+		header = append(header, "#"+strconv.Itoa(i))
+	}
+	return header
+}
+
+// newHeaderMatcher returns the matcher of the input field defined in fc, the headerMatch must have been checked
+func newHeaderMatcher(fc *FieldConfig, headerMatch string) (*headerMatcher, error) {
+	m := &headerMatcher{mode: headerMatch, column: inputColumn(fc.Input)}
+	for _, iter := range append([]string{fc.Input}, fc.Aliases...) {
+		if iter != "" {
+			m.names = append(m.names, m.normalize(iter))
+		}
+	}
+	if fc.Regex != "" {
+		regex, err := regexp.Compile(fc.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %s: %w", fc.Regex, err)
+		}
+		m.regex = regex
+	}
+	return m, nil
+}
+
+func (m *headerMatcher) normalize(name string) string {
+	switch m.mode {
+	case HeaderMatchTrimmed:
+		return strings.TrimSpace(name)
+	case HeaderMatchCaseInsensitive:
+		return strings.ToLower(strings.TrimSpace(name))
+	}
+	return name
+}
+
+// match reports whether the column with the header name is the input field
+func (m *headerMatcher) match(column int, header string) bool {
+	if m.column >= 0 {
+		return column == m.column
+	}
+	if containsString(m.names, m.normalize(header)) {
+		return true
+	}
+	return m.regex != nil && m.regex.MatchString(header)
+}
+
+// HeaderReport is the result of matching the header of an input file against the input fields
+type HeaderReport struct {
+	File    string
+	Matched []string // the input names of the fields found in the header
+	Missing []string // the input names of the fields not found in the header
+	Unused  []string // the header names not read by any field
+}
+
+// headerReport returns the report of the header, which must have been processed by processCSVHeader
+func (p *Pipeline) headerReport(fileName string, header []string) HeaderReport {
+	report := HeaderReport{File: fileName, Matched: []string{}, Missing: []string{}, Unused: []string{}}
+	used := make([]bool, len(header))
+	for _, field := range p.fieldSlice {
+		if field.InputName == "" || p.fieldsMap[field.InputName] != field {
+			continue
+		}
+		positions := field.inputPosArray
+		if field.inputPos >= 0 {
+			positions = []int{field.inputPos}
+		}
+		if len(positions) == 0 {
+			report.Missing = append(report.Missing, field.InputName)
+			continue
+		}
+		report.Matched = append(report.Matched, field.InputName)
+		for _, pos := range positions {
+			used[pos] = true
+		}
+	}
+	for id, iter := range header {
+		if !used[id] {
+			report.Unused = append(report.Unused, iter)
+		}
+	}
+	return report
+}
+
+// checkHeader logs the header report, and returns an error if a required field is missing in the strictHeader mode
+func (p *Pipeline) checkHeader(report HeaderReport, row int) error {
+	p.log.infof(5, "header of %s, matched input fields: %s, unused columns: %s", report.File, report.Matched, report.Unused)
+	if len(report.Missing) > 0 {
+		p.log.infof(1, "header of %s, missing input fields: %s", report.File, report.Missing)
+	}
+
+	for _, iter := range report.Missing {
+		if !p.fieldsMap[iter].required {
+			continue
+		}
+		if p.config.StrictHeader {
+			return &Error{Kind: ErrInputRead, File: report.File, Row: row, Field: iter, Err: errors.New("the required input field is not found in the header")}
+		}
+		p.log.errorf("required input field [%s] is not found in the header of %s", iter, report.File)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package qc
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// recordReader reads the records of the input, the first record is the header
+type recordReader interface {
+	// Read returns the next record and its row number in the input starting from 1, or io.EOF at the end
+	Read() (record []string, row int, err error)
+	Close() error
+}
+
+// IsWorkbookFile reports whether the input file is a xlsx workbook, based on the file extension
+func IsWorkbookFile(fileName string) bool {
+	switch inputExt(fileName) {
+	case ".xlsx", ".xlsm":
+		return true
+	}
+	return false
+}
+
+// newRecordReader returns the reader of the input content, which is read as a xlsx workbook or JSON if the
+// input file is a workbook or a JSON file, or as csv content otherwise. The csv and JSON content
+// is decoded from the configured encoding
+func (p *Pipeline) newRecordReader(fileName string, input io.Reader) (recordReader, error) {
+	if IsWorkbookFile(fileName) {
+		return newSheetReader(input, fileName, p.config.InputSheet, p.config.HeaderRow)
+	}
+	input, err := DecodeInput(input, p.config.Encoding)
+	if err != nil {
+		return nil, &Error{Kind: ErrConfigInvalid, File: fileName, Err: err}
+	}
+	if IsJSONFile(fileName) {
+		return newJSONReader(input, fileName, p.fieldSlice, p.config.SourceFileField)
+	}
+	return newCSVReader(input, fileName, p.config.InputOptions)
+}
+
+// csvReader reads the records of the csv content
+type csvReader struct {
+	reader   *csv.Reader
+	row      int
+	variable bool // whether the records may have different field counts
+	header   int  // the number of fields in the header, the shorter records are filled up with empty values
+}
+
+// newCSVReader returns the reader of the csv content in input in the dialect of options,
+// the lines above the header are skipped
+func newCSVReader(input io.Reader, fileName string, options InputOptions) (*csvReader, error) {
+	comma, comment, err := options.dialect()
+	if err != nil {
+		return nil, &Error{Kind: ErrConfigInvalid, File: fileName, Err: err}
+	}
+
+	r := &csvReader{variable: options.VariableFields}
+	if options.SkipRows > 0 {
+		buf := bufio.NewReader(input)
+		for r.row < options.SkipRows {
+			if _, err := buf.ReadString('\n'); err != nil {
+				break
+			}
+			r.row++
+		}
+		input = buf
+	}
+
+	r.reader = csv.NewReader(input)
+	r.reader.Comma = comma
+	r.reader.Comment = comment
+	r.reader.LazyQuotes = options.LazyQuotes
+	r.reader.TrimLeadingSpace = options.TrimLeadingSpace
+	if options.VariableFields {
+		r.reader.FieldsPerRecord = -1
+	}
+	return r, nil
+}
+
+func (r *csvReader) Read() ([]string, int, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, r.row, err
+	}
+	r.row++
+	if err != nil || !r.variable {
+		return record, r.row, err
+	}
+
+	if r.header == 0 {
+		r.header = len(record)
+	}
+	for len(record) < r.header {
+		record = append(record, "")
+	}
+	return record, r.row, nil
+}
+
+func (r *csvReader) Close() error {
+	return nil
+}
+
+// dialect returns the delimiter and comment characters of the csv input
+func (o InputOptions) dialect() (comma rune, comment rune, err error) {
+	if o.SkipRows < 0 {
+		return 0, 0, fmt.Errorf("invalid skipRows %d, should not be negative", o.SkipRows)
+	}
+	if comma, err = parseDelimiter(o.Delimiter); err != nil {
+		return 0, 0, err
+	}
+	if comment, err = parseComment(o.Comment); err != nil {
+		return 0, 0, err
+	}
+	if comma == comment {
+		return 0, 0, fmt.Errorf("the comment character %q is the same as the delimiter", o.Comment)
+	}
+	return comma, comment, nil
+}
+
+// parseDelimiter converts the delimiter setting into the delimiter character, `,` if empty
+func parseDelimiter(value string) (rune, error) {
+	switch value {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	r, err := inputRune(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid delimiter %q: %w", value, err)
+	}
+	return r, nil
+}
+
+// parseComment converts the comment setting into the comment character, 0 if empty
+func parseComment(value string) (rune, error) {
+	if value == "" {
+		return 0, nil
+	}
+	r, err := inputRune(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid comment %q: %w", value, err)
+	}
+	return r, nil
+}
+
+// inputRune returns the single character in value, which is allowed as the delimiter or comment of the csv input
+func inputRune(value string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(value)
+	if size != len(value) {
+		return 0, errors.New("should be a single character")
+	}
+	if r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, errors.New("the character is not allowed")
+	}
+	return r, nil
+}
+
+// sheetReader reads the rows of a sheet in the xlsx workbook through the excelize row iterator
+type sheetReader struct {
+	xlsx   *excelize.File
+	rows   *excelize.Rows
+	row    int
+	header int // the number of columns in the header, the shorter rows are filled up with empty values
+}
+
+// newSheetReader opens the sheet of the workbook in input, the sheetName defaults to the first sheet and
+// the rows before headerRow are skipped
+func newSheetReader(input io.Reader, fileName string, sheetName string, headerRow int) (*sheetReader, error) {
+	if headerRow < 0 {
+		return nil, &Error{Kind: ErrConfigInvalid, File: fileName, Err: fmt.Errorf("invalid headerRow %d, should be a row number starting from 1", headerRow)}
+	}
+
+	xlsx, err := excelize.OpenReader(input)
+	if err != nil {
+		return nil, &Error{Kind: ErrInputRead, File: fileName, Err: err}
+	}
+	if sheetName == "" {
+		sheetName = xlsx.GetSheetName(0)
+	}
+	rows, err := xlsx.Rows(sheetName)
+	if err != nil {
+		_ = xlsx.Close()
+		return nil, &Error{Kind: ErrInputRead, File: fileName, Err: fmt.Errorf("sheet %s: %w", sheetName, err)}
+	}
+
+	r := &sheetReader{xlsx: xlsx, rows: rows}
+	for r.row < headerRow-1 && r.rows.Next() {
+		r.row++
+	}
+	return r, nil
+}
+
+func (r *sheetReader) Read() ([]string, int, error) {
+	for r.rows.Next() {
+		r.row++
+		record, err := r.rows.Columns()
+		if err != nil {
+			return nil, r.row, err
+		}
+		if isEmptyRecord(record) {
+			// skip the empty rows as the csv reader skips the empty lines
+			continue
+		}
+
+		if r.header == 0 {
+			r.header = len(record)
+		}
+		for len(record) < r.header {
+			record = append(record, "")
+		}
+		return record, r.row, nil
+	}
+	if err := r.rows.Error(); err != nil {
+		return nil, r.row, err
+	}
+	return nil, r.row, io.EOF
+}
+
+func (r *sheetReader) Close() error {
+	_ = r.rows.Close()
+	return r.xlsx.Close()
+}
+
+func isEmptyRecord(record []string) bool {
+	for _, iter := range record {
+		if iter != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadSheetHeader reads the header row of the sheet in the xlsx workbook in input, the sheetName defaults to
+// the first sheet and the headerRow defaults to the first non-empty row
+func ReadSheetHeader(input io.Reader, sheetName string, headerRow int) ([]string, error) {
+	r, err := newSheetReader(input, "", sheetName, headerRow)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	header, row, err := r.Read()
+	if err != nil {
+		return nil, &Error{Kind: ErrInputRead, Row: row, Err: err}
+	}
+	return header, nil
+}
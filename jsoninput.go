@@ -0,0 +1,212 @@
+package qc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// IsJSONFile reports whether the input file is a JSON array of objects (.json) or JSON Lines (.jsonl, .ndjson),
+// based on the file extension
+func IsJSONFile(fileName string) bool {
+	switch inputExt(fileName) {
+	case ".json", ".jsonl", ".ndjson":
+		return true
+	}
+	return false
+}
+
+// dynamicHeader is implemented by the readers whose header may change from record to record
+type dynamicHeader interface {
+	// header returns the header of the last record read, and whether it differs from the previous one
+	header() (header []string, changed bool)
+}
+
+// jsonReader reads the objects of the JSON input as records. The header is made of the input names of the
+// fields, which are the dotted paths of the values in the objects, e.g. fields.assignee.displayName.
+// The values in the array under the path of a subfile field are read as repeated columns, thus the header
+// changes with the length of the arrays.
+type jsonReader struct {
+	decoder *json.Decoder
+	array   bool // the input is a JSON array of objects rather than JSON Lines
+	paths   []string
+	subfile map[string]bool
+	row     int
+
+	started bool
+	pending []string // the first record, which is returned after the header
+	current []string // the header of the last record
+	changed bool
+}
+
+// newJSONReader returns the reader of the JSON content, in which the input names of the fields in fieldSlice are
+// the paths of the columns, except the source field which is filled by the source file name
+func newJSONReader(input io.Reader, fileName string, fieldSlice []*Field, source string) (*jsonReader, error) {
+	r := &jsonReader{
+		decoder: json.NewDecoder(input),
+		array:   inputExt(fileName) == ".json",
+		subfile: make(map[string]bool),
+	}
+	r.decoder.UseNumber()
+	for _, iter := range fieldSlice {
+		if iter.InputName == "" || iter.InputName == source || containsString(r.paths, iter.InputName) {
+			continue
+		}
+		r.paths = append(r.paths, iter.InputName)
+		if iter.isSubfile() {
+			r.subfile[iter.InputName] = true
+		}
+	}
+
+	if r.array {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return r, nil
+		}
+		if err != nil {
+			return nil, &Error{Kind: ErrInputRead, File: fileName, Err: err}
+		}
+		if token != json.Delim('[') {
+			return nil, &Error{Kind: ErrInputRead, File: fileName, Err: fmt.Errorf("the content is not an array of objects")}
+		}
+	}
+	return r, nil
+}
+
+// Read returns the header on the first call, and then the records of the objects
+func (r *jsonReader) Read() ([]string, int, error) {
+	if r.pending != nil {
+		record := r.pending
+		r.pending = nil
+		return record, r.row, nil
+	}
+
+	obj, err := r.next()
+	if err != nil {
+		return nil, r.row, err
+	}
+	header, record := r.flatten(obj)
+	if !r.started {
+		r.started = true
+		r.current, r.pending = header, record
+		return header, r.row, nil
+	}
+
+	r.changed = !equalStrings(r.current, header)
+	r.current = header
+	return record, r.row, nil
+}
+
+func (r *jsonReader) header() ([]string, bool) {
+	return r.current, r.changed
+}
+
+func (r *jsonReader) Close() error {
+	return nil
+}
+
+// next decodes the next object
+func (r *jsonReader) next() (map[string]interface{}, error) {
+	if r.array && !r.decoder.More() {
+		return nil, io.EOF
+	}
+	var obj map[string]interface{}
+	if err := r.decoder.Decode(&obj); err != nil {
+		if err == io.EOF && r.array {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	r.row++
+	return obj, nil
+}
+
+// flatten returns the header and the values of the paths in the object
+func (r *jsonReader) flatten(obj map[string]interface{}) (header []string, record []string) {
+	for _, path := range r.paths {
+		value := lookupPath(obj, path)
+		if items, ok := value.([]interface{}); ok && r.subfile[path] {
+			for _, item := range items {
+				header = append(header, path)
+				record = append(record, jsonText(item))
+			}
+			continue
+		}
+		header = append(header, path)
+		record = append(record, jsonText(value))
+	}
+	return header, record
+}
+
+// lookupPath returns the value at the dotted path in the value. If an array is met in the middle of the path,
+// the rest of the path is looked up in each item and the results are returned as an array.
+func lookupPath(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// the key may include dots
+		if result, ok := v[path]; ok {
+			return result
+		}
+		for i := strings.Index(path, "."); i >= 0; i = nextDot(path, i) {
+			if child, ok := v[path[:i]]; ok {
+				return lookupPath(child, path[i+1:])
+			}
+		}
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			found := lookupPath(item, path)
+			if items, ok := found.([]interface{}); ok {
+				result = append(result, items...)
+			} else if found != nil {
+				result = append(result, found)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func nextDot(path string, i int) int {
+	next := strings.Index(path[i+1:], ".")
+	if next < 0 {
+		return -1
+	}
+	return i + 1 + next
+}
+
+// jsonText returns the text of a JSON value, the objects and arrays are returned as JSON
+func jsonText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(value)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package qc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonlWriter writes the records into a JSON Lines file, one object per record keyed by the output names.
+// The int and float values are written as JSON numbers, and the rows of the nested subfiles are written as
+// arrays under the subfile names.
+type jsonlWriter struct {
+	pendingFile
+	buf       *bufio.Writer
+	header    []string
+	funcCell  []bool
+	emptyFunc bool // write null instead of the formula for the func fields
+	nextRow   int
+	nested    []*nestedRows
+}
+
+func newJSONLWriter(fileName string, header []string, funcCell []bool, emptyFunc bool) (*jsonlWriter, error) {
+	w := &jsonlWriter{
+		header:    header,
+		funcCell:  funcCell,
+		emptyFunc: emptyFunc,
+		nextRow:   defaultHeadingRow,
+	}
+	if err := w.create(fileName); err != nil {
+		return nil, err
+	}
+	w.buf = bufio.NewWriter(w.file)
+	return w, nil
+}
+
+// nest returns the writer keeping the subfile rows of the current record, which are written with the next record
+func (w *jsonlWriter) nest(name string, header []string, funcCell []bool, emptyFunc bool) *nestedRows {
+	rows := &nestedRows{name: name, header: header, funcCell: funcCell, emptyFunc: emptyFunc}
+	w.nested = append(w.nested, rows)
+	return rows
+}
+
+// AddRow writes a record as a line of JSON object, the sheetName is not applicable
+func (w *jsonlWriter) AddRow(sheetName string, data []interface{}) error {
+	w.nextRow++
+	var obj bytes.Buffer
+	if err := encodeObject(&obj, w.header, data, w.funcCell, w.emptyFunc, w.nextRow); err != nil {
+		return err
+	}
+	for _, iter := range w.nested {
+		obj.Truncate(obj.Len() - 1)
+		if obj.Len() > 1 {
+			obj.WriteByte(',')
+		}
+		encodeValue(&obj, iter.name)
+		obj.WriteString(":[")
+		for id, row := range iter.rows {
+			if id > 0 {
+				obj.WriteByte(',')
+			}
+			obj.Write(row)
+		}
+		obj.WriteString("]}")
+		iter.reset()
+	}
+	obj.WriteByte('\n')
+	_, err := w.buf.Write(obj.Bytes())
+	return err
+}
+
+// Flush writes the buffered records into the temporary file
+func (w *jsonlWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+func (w *jsonlWriter) save() error {
+	return w.commit(w.Flush)
+}
+
+// nestedRows keeps the encoded subfile rows until the parent record is written
+type nestedRows struct {
+	name      string
+	header    []string
+	funcCell  []bool
+	emptyFunc bool
+	rows      [][]byte
+}
+
+// AddRow keeps a subfile row for the current parent record, the sheetName is not applicable
+func (n *nestedRows) AddRow(sheetName string, data []interface{}) error {
+	var obj bytes.Buffer
+	if err := encodeObject(&obj, n.header, data, n.funcCell, n.emptyFunc, len(n.rows)+defaultHeadingRow+1); err != nil {
+		return err
+	}
+	n.rows = append(n.rows, obj.Bytes())
+	return nil
+}
+
+// Flush does nothing as the rows are written by the parent writer
+func (n *nestedRows) Flush() error {
+	return nil
+}
+
+// reset drops the rows of the current parent record, which is called once the parent record is written or filtered out
+func (n *nestedRows) reset() {
+	n.rows = n.rows[:0]
+}
+
+// encodeObject writes the data as a JSON object keyed by header, the func fields are written as the formula
+// text with the row number, or null if emptyFunc
+func encodeObject(buf *bytes.Buffer, header []string, data []interface{}, funcCell []bool, emptyFunc bool, row int) error {
+	if len(data) != len(header) {
+		return fmt.Errorf("number of data items (%d) does not equal the number of columns (%d)", len(data), len(header))
+	}
+
+	buf.WriteByte('{')
+	for i, iter := range data {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodeValue(buf, header[i])
+		buf.WriteByte(':')
+		switch {
+		case funcCell[i] && emptyFunc:
+			buf.WriteString("null")
+		case funcCell[i]:
+			encodeValue(buf, funcCellValue(iter, row))
+		default:
+			encodeValue(buf, iter)
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func encodeValue(buf *bytes.Buffer, value interface{}) {
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		// e.g. the NaN and Inf float values, which are not valid JSON numbers
+		data.Reset()
+		_ = enc.Encode(formatValue(value))
+	}
+	// drop the newline added by the encoder
+	buf.Write(bytes.TrimSuffix(data.Bytes(), []byte{'\n'}))
+}
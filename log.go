@@ -0,0 +1,50 @@
+package qc
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	LOG_DEBUG = 10
+	LOG_INFO  = 5
+)
+
+// Logger writes the progress and error messages of a Pipeline
+type Logger struct {
+	Out *logrus.Logger
+
+	// the info level for troubleshooting, messages with a higher level are discarded
+	Level int
+
+	// display the warning information, e.g. duplicated keys in the lookup files
+	Warning bool
+}
+
+// NewLogger returns a Logger writing to logrus' standard logger with the default info level
+func NewLogger() *Logger {
+	return &Logger{
+		Out:   logrus.StandardLogger(),
+		Level: LOG_INFO,
+	}
+}
+
+// the below functions discard the message if the Logger is nil
+
+func (l *Logger) infof(level int, format string, args ...interface{}) {
+	if l != nil && l.Level >= level {
+		l.Out.Printf(format, args...)
+	}
+}
+
+func (l *Logger) errorf(format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.Out.Errorf(format, args...)
+}
+
+func (l *Logger) warnf(format string, args ...interface{}) {
+	if l != nil && l.Warning {
+		l.Out.Warnf(format, args...)
+	}
+}
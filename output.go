@@ -0,0 +1,380 @@
+package qc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// the output formats, selected by the format setting or the extension of the output file name
+const (
+	FormatXLSX   = "xlsx"
+	FormatCSV    = "csv"
+	FormatTSV    = "tsv"
+	FormatJSONL  = "jsonl"
+	FormatSQLite = "sqlite"
+)
+
+// the values of the funcCells setting, which decides how the func fields are written in the csv, tsv and jsonl outputs
+const (
+	FuncCellsFormula = "formula" // write the formula text, e.g. =LEFT(C2,3)
+	FuncCellsEmpty   = "empty"   // leave the cell empty, or null in the jsonl output
+)
+
+// RecordWriter writes the converted records into an output, File is the xlsx implementation
+type RecordWriter interface {
+	// AddRow adds a record, the data includes one value for each output field
+	AddRow(sheetName string, data []interface{}) error
+	// Flush ends writing the records
+	Flush() error
+}
+
+// outputFile is a file written in a run, it is only saved when the whole run succeeds
+type outputFile interface {
+	save() error
+	// close releases the resources and removes the temporary files
+	close()
+}
+
+// outputSpec defines an output of a run, either the main output or a subfile output
+type outputSpec struct {
+	fileName  string
+	sheetName string
+	format    string
+	funcCells string
+	tableMode string
+}
+
+// outputFormat returns the output format set by format, or detected from the extension of fileName
+func outputFormat(fileName string, format string) (string, error) {
+	if format != "" {
+		switch strings.ToLower(format) {
+		case FormatXLSX, FormatCSV, FormatTSV, FormatJSONL, FormatSQLite:
+			return strings.ToLower(format), nil
+		}
+		return "", fmt.Errorf("unknown output format %s", format)
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".tsv", ".tab":
+		return FormatTSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".sqlite", ".sqlite3", ".db":
+		return FormatSQLite, nil
+	}
+	return FormatXLSX, nil
+}
+
+func checkFuncCells(funcCells string) error {
+	switch funcCells {
+	case "", FuncCellsFormula, FuncCellsEmpty:
+		return nil
+	}
+	return fmt.Errorf("unknown funcCells option %s, should be %s or %s", funcCells, FuncCellsFormula, FuncCellsEmpty)
+}
+
+// outputs keeps the files written in a run
+type outputs struct {
+	log   *Logger
+	names []string // the file names in the creation order
+	files map[string]outputFile
+}
+
+func newOutputs(log *Logger) *outputs {
+	return &outputs{
+		log:   log,
+		files: make(map[string]outputFile),
+	}
+}
+
+// create returns the writer for the output fields in fieldSlice
+func (o *outputs) create(fieldSlice []*Field, spec outputSpec) (RecordWriter, error) {
+	format, err := outputFormat(spec.fileName, spec.format)
+	if err == nil {
+		err = checkFuncCells(spec.funcCells)
+	}
+	if err == nil {
+		err = checkTableMode(spec.tableMode)
+	}
+	if err != nil {
+		return nil, &Error{Kind: ErrConfigInvalid, File: spec.fileName, Err: err}
+	}
+
+	header, width, funcCell := outputColumns(fieldSlice)
+	existing := o.files[spec.fileName]
+
+	switch format {
+	case FormatXLSX:
+		book, ok := existing.(*workbook)
+		if existing != nil && !ok {
+			return nil, &Error{Kind: ErrConfigInvalid, File: spec.fileName, Err: fmt.Errorf("the file is used by outputs in different formats")}
+		}
+		if book == nil {
+			xlsx, created, err := OpenWorkbook(spec.fileName, o.log)
+			if err != nil {
+				return nil, err
+			}
+			book = &workbook{fileName: spec.fileName, xlsx: xlsx, created: created}
+			o.add(spec.fileName, book)
+		}
+
+		xlsFile, err := NewExcel(book.xlsx, spec.sheetName, header, width, funcCell, o.log)
+		if err != nil {
+			return nil, &Error{Kind: ErrOutputWrite, File: spec.fileName, Err: fmt.Errorf("sheet %s: %w", spec.sheetName, err)}
+		}
+		book.sheets = append(book.sheets, xlsFile)
+		return xlsFile, nil
+
+	case FormatSQLite:
+		db, ok := existing.(*database)
+		if existing != nil && !ok {
+			return nil, &Error{Kind: ErrConfigInvalid, File: spec.fileName, Err: fmt.Errorf("the file is used by outputs in different formats")}
+		}
+		if db == nil {
+			if db, err = openDatabase(spec.fileName); err != nil {
+				return nil, &Error{Kind: ErrOutputWrite, File: spec.fileName, Err: err}
+			}
+			o.add(spec.fileName, db)
+		}
+		table, err := db.createTable(spec.sheetName, fieldSlice, spec.tableMode, spec.funcCells == FuncCellsEmpty)
+		if err != nil {
+			return nil, &Error{Kind: ErrOutputWrite, File: spec.fileName, Err: err}
+		}
+		return table, nil
+	}
+
+	if existing != nil {
+		return nil, &Error{Kind: ErrConfigInvalid, File: spec.fileName, Err: fmt.Errorf("the %s file can only be used by one output", format)}
+	}
+	var writer interface {
+		RecordWriter
+		outputFile
+	}
+	switch format {
+	case FormatJSONL:
+		writer, err = newJSONLWriter(spec.fileName, header, funcCell, spec.funcCells == FuncCellsEmpty)
+	case FormatTSV:
+		writer, err = newDelimitedWriter(spec.fileName, '\t', header, funcCell, spec.funcCells == FuncCellsEmpty)
+	default:
+		writer, err = newDelimitedWriter(spec.fileName, ',', header, funcCell, spec.funcCells == FuncCellsEmpty)
+	}
+	if err != nil {
+		return nil, &Error{Kind: ErrOutputWrite, File: spec.fileName, Err: err}
+	}
+	o.add(spec.fileName, writer)
+	return writer, nil
+}
+
+// nest returns the writer which nests the subfile rows as an array named name under the records of parent,
+// only the jsonl output supports the nested rows
+func (o *outputs) nest(parent RecordWriter, name string, fieldSlice []*Field, funcCells string) (RecordWriter, error) {
+	writer, ok := parent.(*jsonlWriter)
+	if !ok {
+		return nil, &Error{Kind: ErrConfigInvalid, Err: fmt.Errorf("subfile %s: the nested rows are only supported in the %s output", name, FormatJSONL)}
+	}
+	if err := checkFuncCells(funcCells); err != nil {
+		return nil, &Error{Kind: ErrConfigInvalid, Err: fmt.Errorf("subfile %s: %w", name, err)}
+	}
+	header, _, funcCell := outputColumns(fieldSlice)
+	return writer.nest(name, header, funcCell, funcCells == FuncCellsEmpty), nil
+}
+
+func (o *outputs) add(fileName string, file outputFile) {
+	o.names = append(o.names, fileName)
+	o.files[fileName] = file
+}
+
+// save saves all the output files
+func (o *outputs) save() error {
+	for _, fileName := range o.names {
+		if err := o.files[fileName].save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *outputs) close() {
+	for _, file := range o.files {
+		file.close()
+	}
+}
+
+// outputColumns returns the heading, width and whether it is a func field of the output fields
+func outputColumns(fieldSlice []*Field) (header []string, width []int, funcCell []bool) {
+	for _, iter := range fieldSlice {
+		if iter.hasOutput() {
+			// if the output name is not defined, then the field won't be output
+			width = append(width, iter.Width)
+			header = append(header, iter.OutputName)
+			funcCell = append(funcCell, iter.converterType == ConverterTypeFunc)
+		}
+	}
+	return header, width, funcCell
+}
+
+// workbook is a xlsx output file, the sheets with the same output file name share a single workbook
+type workbook struct {
+	fileName string
+	xlsx     *excelize.File
+	created  bool // the file is newly created thus the default sheet must be removed
+	sheets   []*File
+}
+
+// save flushes all the sheets and saves the workbook
+func (w *workbook) save() error {
+	defaultSheetUsed := false
+	for _, iter := range w.sheets {
+		if err := iter.Flush(); err != nil {
+			return &Error{Kind: ErrOutputWrite, File: w.fileName, Err: fmt.Errorf("sheet %s: %w", iter.SheetName, err)}
+		}
+		if strings.EqualFold(iter.SheetName, "Sheet1") {
+			defaultSheetUsed = true
+		}
+	}
+	if w.created && !defaultSheetUsed {
+		// delete the default sheet of the new file
+		w.xlsx.DeleteSheet("Sheet1")
+	}
+	if err := w.xlsx.SaveAs(w.fileName); err != nil {
+		return &Error{Kind: ErrOutputWrite, File: w.fileName, Err: err}
+	}
+	return nil
+}
+
+// close releases the temporary files of the stream writers
+func (w *workbook) close() {
+	_ = w.xlsx.Close()
+}
+
+// pendingFile is a text output written into a temporary file in the same directory, which is renamed to
+// the output file name when saved
+type pendingFile struct {
+	fileName string
+	file     *os.File
+	saved    bool
+}
+
+func (f *pendingFile) create(fileName string) error {
+	file, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// the temporary file is created with 0600
+	_ = file.Chmod(0644)
+
+	f.fileName = fileName
+	f.file = file
+	return nil
+}
+
+// commit closes and renames the temporary file, flush writes the buffered content before closing
+func (f *pendingFile) commit(flush func() error) error {
+	err := flush()
+	if err == nil {
+		err = f.file.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.file.Name(), f.fileName)
+	}
+	if err != nil {
+		return &Error{Kind: ErrOutputWrite, File: f.fileName, Err: err}
+	}
+	f.saved = true
+	return nil
+}
+
+func (f *pendingFile) close() {
+	if f.saved {
+		return
+	}
+	_ = f.file.Close()
+	_ = os.Remove(f.file.Name())
+}
+
+// delimitedWriter writes the records into a csv or tsv file
+type delimitedWriter struct {
+	pendingFile
+	writer    *csv.Writer
+	funcCell  []bool
+	emptyFunc bool // leave the func cells empty instead of writing the formula
+	nextRow   int
+}
+
+func newDelimitedWriter(fileName string, comma rune, header []string, funcCell []bool, emptyFunc bool) (*delimitedWriter, error) {
+	w := &delimitedWriter{
+		funcCell:  funcCell,
+		emptyFunc: emptyFunc,
+		nextRow:   defaultHeadingRow,
+	}
+	if err := w.create(fileName); err != nil {
+		return nil, err
+	}
+	w.writer = csv.NewWriter(w.file)
+	w.writer.Comma = comma
+	if err := w.writer.Write(header); err != nil {
+		w.close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// AddRow adds a row of data to the file, the sheetName is not applicable
+func (w *delimitedWriter) AddRow(sheetName string, data []interface{}) error {
+	if len(data) != len(w.funcCell) {
+		return fmt.Errorf("number of data items (%d) does not equal the number of columns (%d)", len(data), len(w.funcCell))
+	}
+
+	w.nextRow++
+	row := make([]string, 0, len(data))
+	for i, iter := range data {
+		switch {
+		case w.funcCell[i] && w.emptyFunc:
+			row = append(row, "")
+		case w.funcCell[i]:
+			row = append(row, funcCellValue(iter, w.nextRow))
+		default:
+			row = append(row, formatValue(iter))
+		}
+	}
+	return w.writer.Write(row)
+}
+
+// Flush writes the buffered records into the temporary file
+func (w *delimitedWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *delimitedWriter) save() error {
+	return w.commit(w.Flush)
+}
+
+// funcCellValue returns the formula text of a func field in the row
+func funcCellValue(formula interface{}, row int) string {
+	// replace the row name with actual row value
+	return "=" + strings.Replace(fmt.Sprint(formula), "{row}", strconv.Itoa(row), -1)
+}
+
+// formatValue converts the value produced by the converters into the text written in the csv file
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,287 @@
+package qc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Pipeline converts the csv records into the output files defined in a CSVConvertorConfig.
+// All the state of the conversion is kept in the Pipeline rather than in the config, thus
+// multiple pipelines can run in the same process; a single Pipeline must not Run concurrently.
+type Pipeline struct {
+	config *CSVConvertorConfig
+	log    *Logger
+
+	// below attributes to keep the converted result
+	fieldSlice  []*Field          // define the output fields setting based on the Fields configs
+	fieldsMap   map[string]*Field // memory map for quick references to fieldSlice,using input csv's field name as key
+	subfiles    []*SubFile
+	subfilesMap map[string]*SubFile
+	lookupMap   map[string]*Lookup
+	filters     []*Filter
+	reports     []HeaderReport // the header reports of the input files in the last run
+}
+
+// NewPipeline loads the lookup files and prepares the fields, subfiles and filters defined in config.
+// If logger is nil, the messages are written by NewLogger()
+func NewPipeline(config *CSVConvertorConfig, logger *Logger) (*Pipeline, error) {
+	if logger == nil {
+		logger = NewLogger()
+	}
+
+	p := &Pipeline{
+		config:      config,
+		log:         logger,
+		subfilesMap: make(map[string]*SubFile),
+		lookupMap:   make(map[string]*Lookup),
+	}
+
+	if err := checkHeaderMatch(config.HeaderMatch); err != nil {
+		return nil, &Error{Kind: ErrConfigInvalid, Path: "headerMatch", Err: err}
+	}
+
+	// read the mapping file and store the mapping in memory
+	for _, iter := range config.Lookups {
+		lookup, err := loadLookup(iter, p.log)
+		if err != nil {
+			return nil, err
+		}
+		p.lookupMap[lookup.Name] = lookup
+	}
+
+	// processing the subFiles
+	for id, iter := range config.Subfiles {
+		subFile := *iter
+		if err := checkFieldConfigs(fmt.Sprintf("subfile.%d.fields", id), subFile.Fields); err != nil {
+			return nil, err
+		}
+		if err := subFile.prepareSplit(subFile.Fields); err != nil {
+			var splitErr *Error
+			if errors.As(err, &splitErr) {
+				splitErr.Path = fmt.Sprintf("subfile.%d.%s", id, splitErr.Path)
+			}
+			return nil, err
+		}
+		var err error
+		subFile.fieldsMap, subFile.fieldSlice, err = p.formalizeFieldConfigs(fmt.Sprintf("subfile.%d.fields", id), subFile.Fields)
+		if err != nil {
+			return nil, err
+		}
+		// update the field location id directly
+		for id, field := range subFile.fieldSlice {
+			if field.InputName != "" {
+				field.inputPos = id
+			}
+		}
+		subFile.filters = p.prepareFilters(subFile.Filters, subFile.fieldSlice)
+		//add the subFile name to map
+		p.subfiles = append(p.subfiles, &subFile)
+		p.subfilesMap[subFile.Name] = &subFile
+		p.log.infof(6, "NewPipeline add subFile [%s]", subFile.Name)
+	}
+
+	if err := checkFieldConfigs("fields", config.Fields); err != nil {
+		return nil, err
+	}
+	fieldConfigs := config.Fields
+	if config.SourceFileField != "" && !hasInputField(fieldConfigs, config.SourceFileField) {
+		// output the source file names in the last column if no field refers to them
+		fieldConfigs = append(fieldConfigs[:len(fieldConfigs):len(fieldConfigs)], &FieldConfig{
+			Input:  config.SourceFileField,
+			Output: config.SourceFileField,
+			Width:  defaultFieldWidth,
+		})
+	}
+	var err error
+	if p.fieldsMap, p.fieldSlice, err = p.formalizeFieldConfigs("fields", fieldConfigs); err != nil {
+		return nil, err
+	}
+	for id, subFile := range p.subfiles {
+		if err := subFile.resolveMasterFields(p.fieldSlice); err != nil {
+			var refErr *Error
+			if errors.As(err, &refErr) {
+				refErr.Path = fmt.Sprintf("subfile.%d", id)
+			}
+			return nil, err
+		}
+	}
+
+	// processing the filters
+	p.filters = p.prepareFilters(config.Filters, p.fieldSlice)
+
+	return p, nil
+}
+
+// prepareFilters copies the filters with the positions of their fields in the converted records of fieldSlice
+func (p *Pipeline) prepareFilters(filters []*Filter, fieldSlice []*Field) []*Filter {
+	result := make([]*Filter, 0, len(filters))
+	for _, iter := range filters {
+		filter := *iter
+		filter.fieldPos = getOutputFieldPos(filter.Field, fieldSlice)
+		if filter.fieldPos >= 0 {
+			filter.valueMap = make(map[string]int)
+			for _, field := range filter.Values {
+				filter.valueMap[field] = 1
+			}
+		} else {
+			p.log.errorf("filter field [%s] is not defined in the field list", filter.Field)
+		}
+		result = append(result, &filter)
+	}
+	return result
+}
+
+// Run reads the csv content, or the xlsx workbook or JSON content if the first configured input file is a workbook
+// or a JSON file, from input, and saves the converted records into the main output file and the subfile records
+// into their own output files
+func (p *Pipeline) Run(ctx context.Context, input io.Reader) error {
+	return p.run(ctx, []inputSource{{fileName: p.config.Input.first(), reader: input}})
+}
+
+// RunFiles reads the configured input files in turn, with the glob patterns expanded, and saves the records of
+// all the files into the same outputs. The header of each file is matched again, thus the files may have different
+// columns. The standard input is read if no input file is configured
+func (p *Pipeline) RunFiles(ctx context.Context) error {
+	fileNames, err := p.config.Input.Expand()
+	if err != nil {
+		return err
+	}
+	if len(fileNames) == 0 {
+		return p.run(ctx, []inputSource{{reader: os.Stdin}})
+	}
+
+	sources := make([]inputSource, 0, len(fileNames))
+	for _, iter := range fileNames {
+		sources = append(sources, inputSource{fileName: iter})
+	}
+	return p.run(ctx, sources)
+}
+
+// HeaderReports returns the reports of matching the input fields against the header of each input file
+// read in the last run
+func (p *Pipeline) HeaderReports() []HeaderReport {
+	return append([]HeaderReport{}, p.reports...)
+}
+
+// inputSource is an input of a run
+type inputSource struct {
+	fileName string
+	reader   io.Reader // the content of the input, the file is opened if nil
+}
+
+func (p *Pipeline) run(ctx context.Context, sources []inputSource) error {
+	p.reports = nil
+	files := newOutputs(p.log)
+	defer files.close()
+
+	writer, err := files.create(p.fieldSlice, outputSpec{
+		fileName:  p.config.Output,
+		sheetName: p.config.SheetName,
+		format:    p.config.Format,
+		funcCells: p.config.FuncCells,
+		tableMode: p.config.TableMode,
+	})
+	if err != nil {
+		return err
+	}
+	for _, subFile := range p.subfiles {
+		subFile.saveCount = 0
+		if subFile.Nested {
+			if subFile.writer, err = files.nest(writer, subFile.Name, subFile.fieldSlice, subFile.FuncCells); err != nil {
+				return err
+			}
+			continue
+		}
+		subFile.writer, err = files.create(subFile.fieldSlice, outputSpec{
+			fileName:  subFile.Output,
+			sheetName: subFile.SheetName,
+			format:    subFile.Format,
+			funcCells: subFile.FuncCells,
+			tableMode: subFile.TableMode,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, source := range sources {
+		if err := p.processSource(ctx, source, writer); err != nil {
+			return err
+		}
+	}
+	for _, subFile := range p.subfiles {
+		p.log.infof(3, "process subFile: %s, sheet: %s, total records saved: %d", subFile.Output, subFile.SheetName, subFile.saveCount)
+	}
+
+	return files.save()
+}
+
+// processSource converts the records of the input source, or of each member if it is a zip archive,
+// and saves them into writer
+func (p *Pipeline) processSource(ctx context.Context, source inputSource, writer RecordWriter) error {
+	input := source.reader
+	if input == nil {
+		f, err := OpenInput(source.fileName)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	members, err := decompressInput(input, source.fileName, p.config.InputMember)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if err := p.processMember(ctx, member, writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) processMember(ctx context.Context, member inputMember, writer RecordWriter) error {
+	content, err := member.open()
+	if err != nil {
+		return &Error{Kind: ErrInputRead, File: member.fileName, Err: err}
+	}
+	defer content.Close()
+
+	reader, err := p.newRecordReader(member.fileName, content)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return p.processCSV(ctx, member.fileName, reader, writer)
+}
+
+// loadLookup copies the lookup definition and loads its mapping file, the copy belongs to a single pipeline
+func loadLookup(iter *Lookup, log *Logger) (*Lookup, error) {
+	lookup := *iter
+	// update the LookupOption
+	lookup.lookupOption = LookupOptionConvert(lookup.Option)
+	lookup.converterType, lookup.converter = FieldTypeConvert(lookup.Type)
+	if lookup.converterType == ConverterTypeDefault && lookup.Type != "" {
+		converter, ok, err := newCustomConverter(lookup.Type, nil)
+		if err != nil {
+			return nil, &Error{Kind: ErrConfigInvalid, Field: lookup.Name, Err: err}
+		}
+		if ok {
+			lookup.converterType, lookup.converter = ConverterTypeCustom, converter
+		}
+	}
+	lookup.keyValueMap = make(map[string][]string)
+	lookup.keyValueSlice = make([]*LookupRegex, 0)
+
+	log.infof(6, "Lookup[%s] with option: %s", lookup.Name, lookup.lookupOption.String())
+	log.infof(6, "loadExcelFile processing lookup[%s] in file: %s, sheet: %s", lookup.Name, lookup.FileName, lookup.SheetName)
+	if err := loadLookupExcelFile(&lookup, log); err != nil {
+		return nil, err
+	}
+	return &lookup, nil
+}
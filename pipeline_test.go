@@ -0,0 +1,1040 @@
+package qc
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/vcaesar/tt"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const testCSV = "\ufeffID,Name,Team\n1,alice,red\n2,bob,blue\n3,carol,red\n"
+
+func readSheet(t *testing.T, fileName, sheetName string) [][]string {
+	xlFile, err := excelize.OpenFile(fileName)
+	if err != nil {
+		t.Fatalf("open %s: %s", fileName, err)
+	}
+	defer xlFile.Close()
+
+	rows, err := xlFile.GetRows(sheetName)
+	if err != nil {
+		t.Fatalf("read sheet %s: %s", sheetName, err)
+	}
+	return rows
+}
+
+func TestPipelinesRunConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	configs := []*CSVConvertorConfig{
+		{
+			Output:    filepath.Join(dir, "red.xlsx"),
+			SheetName: "red",
+			Fields:    ParseFieldConfigs("ID,ID,10,int", "Name,Name,20", "Team,Team,10"),
+			Filters:   []*Filter{{Field: "Team", Values: []string{"red"}}},
+		},
+		{
+			Output:    filepath.Join(dir, "names.xlsx"),
+			SheetName: "names",
+			Fields:    ParseFieldConfigs("Name,User,20"),
+		},
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(configs))
+	for i, config := range configs {
+		wg.Add(1)
+		go func(i int, config *CSVConvertorConfig) {
+			defer wg.Done()
+			pipeline, err := NewPipeline(config, nil)
+			if err == nil {
+				err = pipeline.Run(context.Background(), strings.NewReader(testCSV))
+			}
+			errs[i] = err
+		}(i, config)
+	}
+	wg.Wait()
+
+	tt.Nil(t, errs[0])
+	tt.Nil(t, errs[1])
+	tt.Equal(t, [][]string{{"ID", "Name", "Team"}, {"1", "alice", "red"}, {"3", "carol", "red"}},
+		readSheet(t, configs[0].Output, "red"))
+	tt.Equal(t, [][]string{{"User"}, {"alice"}, {"bob"}, {"carol"}},
+		readSheet(t, configs[1].Output, "names"))
+}
+
+func TestPipelineErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ReadConfig(filepath.Join(dir, "missing.yaml"))
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+
+	_, err = NewPipeline(&CSVConvertorConfig{
+		Fields:  ParseFieldConfigs("ID,ID"),
+		Lookups: []*Lookup{{Name: "Team", FileName: filepath.Join(dir, "missing.xlsx"), SheetName: "Team"}},
+	}, nil)
+	tt.True(t, errors.Is(err, ErrLookupLoad))
+
+	// the field definitions failing to parse are not skipped
+	_, err = NewPipeline(&CSVConvertorConfig{Fields: ParseFieldConfigs("ID,ID", "Name")}, nil)
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+	tt.Equal(t, "invalid config (path: fields.1): incorrect field format Name: must have at least 2 parameters separated by `,`", err.Error())
+	_, err = NewPipeline(&CSVConvertorConfig{
+		Fields:   ParseFieldConfigs("ID,ID", "Name,,0,explode,names"),
+		Subfiles: []*SubFile{{Name: "names", Fields: ParseFieldConfigs("value")}},
+	}, nil)
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+	tt.True(t, strings.HasPrefix(err.Error(), "invalid config (path: subfile.0.fields.0)"))
+	_, err = NewPipeline(&CSVConvertorConfig{Fields: []*FieldConfig{{Input: "ID", Output: "ID"}, {Input: "Name", Output: "Name", Regex: "(["}}}, nil)
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+	tt.True(t, strings.HasPrefix(err.Error(), "invalid config (path: fields.1): invalid regex ([: "))
+
+	// the invalid lookup fields are not written as the error text in the cells
+	dict := filepath.Join(dir, "dict.xlsx")
+	writeLookupFile(t, dict, "Team", [][]string{{"Name", "Team"}, {"alice", "red"}})
+	for definition, message := range map[string]string{
+		",T,10,lookup,ID,Nope":     "invalid parameter size 2 for lookup, should have: src index, map name, result index",
+		",T,10,lookup":             "invalid parameter size 0 for lookup, should have: src index, map name, result index",
+		",T,10,lookup,ID,Teams,2":  "undefined lookup map Teams",
+		",T,10,lookup,Name,Team,2": "the referenced field [Name] is not defined prior to the current field",
+		",T,10,lookup,ID,Team,1":   "incorrect map index value 1, should be a number starting from 2",
+		",T,10,constant":           "missing parameter for type constant",
+		"Log Work,,0,subfile":      "missing subfile name",
+	} {
+		_, err = NewPipeline(&CSVConvertorConfig{
+			Fields:  ParseFieldConfigs("ID,ID", definition, "Name,Name"),
+			Lookups: []*Lookup{{Name: "Team", FileName: dict, SheetName: "Team"}},
+		}, nil)
+		tt.True(t, errors.Is(err, ErrConfigInvalid))
+		tt.Equal(t, "invalid config (path: fields.1): "+message, err.Error())
+	}
+
+	output := filepath.Join(dir, "output.xlsx")
+	pipeline, err := NewPipeline(&CSVConvertorConfig{Output: output, SheetName: "data", Fields: ParseFieldConfigs("Key,Key")}, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(testCSV))
+	tt.True(t, errors.Is(err, ErrInputRead))
+	tt.Equal(t, "unable to read input (row: 1): unable to found matched header fields", err.Error())
+	// nothing is written when the run fails
+	tt.False(t, IsFile(output))
+
+	pipeline, err = NewPipeline(&CSVConvertorConfig{Output: filepath.Join(dir, "missing", "output.xlsx"), SheetName: "data", Fields: ParseFieldConfigs("ID,ID")}, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(testCSV))
+	tt.True(t, errors.Is(err, ErrOutputWrite))
+
+	output = filepath.Join(dir, "output.csv")
+	pipeline, err = NewPipeline(&CSVConvertorConfig{Output: output, Fields: ParseFieldConfigs("ID,ID", "Name,Name,10,drop")}, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(testCSV))
+	tt.True(t, errors.Is(err, ErrOutputWrite))
+	tt.Equal(t, "unable to write output (file: "+output+"): input row 2: "+
+		"number of data items (1) does not equal the number of columns (2)", err.Error())
+
+	subfile := filepath.Join(dir, "subfile.csv")
+	pipeline, err = NewPipeline(&CSVConvertorConfig{
+		Output:   output,
+		Fields:   ParseFieldConfigs("ID,ID", "Name,,0,explode,names"),
+		Subfiles: []*SubFile{{Name: "names", Output: subfile, Fields: ParseFieldConfigs("ID,ID", "value,Name,10,drop")}},
+	}, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(testCSV))
+	tt.True(t, errors.Is(err, ErrOutputWrite))
+	tt.True(t, strings.HasPrefix(err.Error(), "unable to write output (file: "+subfile+")"))
+}
+
+func TestPipelineSubfileInSameWorkbook(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.xlsx")
+	config := &CSVConvertorConfig{
+		Output:    output,
+		SheetName: "data",
+		Fields: ParseFieldConfigs(
+			"Issue key,Key,12",
+			",Project,15,func,LEFT(A{row},2)",
+			"Log Work,,0,subfile,JiraLogTime",
+		),
+		Subfiles: []*SubFile{{
+			Name:      "JiraLogTime",
+			SheetName: "Time Spent",
+			Output:    output,
+			Fields:    ParseFieldConfigs("Issue key,Key,12", "value,Reporter,12", "value,Hours,8,sec2hour"),
+		}},
+	}
+	input := "Issue key,Log Work,Log Work\n" +
+		"QC-1,;05/Jan/21 8:45 AM;uid:1;14400,;06/Jan/21 8:45 AM;uid:2;3600\n" +
+		"QC-2,,\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+
+	xlFile, err := excelize.OpenFile(output)
+	tt.Nil(t, err)
+	defer xlFile.Close()
+
+	tt.Equal(t, []string{"data", "Time Spent"}, xlFile.GetSheetList())
+	formula, _ := xlFile.GetCellFormula("data", "B3")
+	tt.Equal(t, "LEFT(A3,2)", formula)
+	width, _ := xlFile.GetColWidth("data", "B")
+	tt.Equal(t, float64(15), width)
+	style, _ := xlFile.GetCellStyle("data", "A1")
+	tt.NotEqual(t, 0, style)
+
+	tt.Equal(t, [][]string{{"Key", "Reporter", "Hours"}, {"QC-1", "uid:1", "4"}, {"QC-1", "uid:2", "1"}},
+		readSheet(t, output, "Time Spent"))
+}
+
+func TestPipelineDelimitedOutput(t *testing.T) {
+	dir := t.TempDir()
+	config := &CSVConvertorConfig{
+		Output: filepath.Join(dir, "output.csv"),
+		Fields: ParseFieldConfigs(
+			"Issue key,Key,12",
+			",Project,15,func,LEFT(A{row},2)",
+			"Log Work,,0,subfile,JiraLogTime",
+		),
+		Subfiles: []*SubFile{{
+			Name:      "JiraLogTime",
+			Output:    filepath.Join(dir, "time.txt"),
+			Format:    "tsv",
+			FuncCells: FuncCellsEmpty,
+			Fields:    ParseFieldConfigs("Issue key,Key,12", "value,Hours,8,sec2hour", ",Check,8,func,B{row}>1"),
+		}},
+	}
+	input := "Issue key,Log Work,Log Work\n" +
+		"\"QC,1\",;05/Jan/21 8:45 AM;uid:1;5400,;06/Jan/21 8:45 AM;uid:2;3600\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+
+	data, err := os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Project\n\"QC,1\",\"=LEFT(A2,2)\"\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[0].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key\tHours\tCheck\nQC,1\t1.5\t\nQC,1\t1\t\n", string(data))
+
+	// a csv file can not be shared by the outputs
+	config.Subfiles[0].Output = config.Output
+	config.Subfiles[0].Format = ""
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(input))
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+}
+
+func TestPipelineJSONLOutput(t *testing.T) {
+	dir := t.TempDir()
+	config := &CSVConvertorConfig{
+		Output: filepath.Join(dir, "output.jsonl"),
+		Fields: ParseFieldConfigs(
+			"Issue key,Key,12",
+			"Status,Status,8",
+			"Votes,Votes,8,int",
+			"Ratio,Ratio,8,float",
+			",Project,15,func,LEFT(A{row},2)",
+			"Log Work,,0,subfile,JiraLogTime",
+			"Components,,0,subfile,components",
+		),
+		Subfiles: []*SubFile{
+			{
+				Name:   "JiraLogTime",
+				Nested: true,
+				Fields: ParseFieldConfigs("value,Reporter,12", "value,Hours,8,sec2hour"),
+			},
+			{
+				Name:   "components",
+				Output: filepath.Join(dir, "components.ndjson"),
+				Fields: ParseFieldConfigs("Issue key,Key,12", "value,Component,12"),
+			},
+		},
+		Filters: []*Filter{{Field: "Status", Values: []string{"open"}}},
+	}
+	input := "Issue key,Status,Votes,Ratio,Log Work,Log Work,Components\n" +
+		"QC-1,open,3,0.5,;05/Jan/21 8:45 AM;uid:1;5400,;06/Jan/21 8:45 AM;uid:2;3600,<api>\n" +
+		"QC-2,closed,x,1,;07/Jan/21 8:45 AM;uid:3;3600,,\n" +
+		"QC-3,open,,,,,ui\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+
+	data, err := os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, `{"Key":"QC-1","Status":"open","Votes":3,"Ratio":0.5,"Project":"=LEFT(A2,2)","JiraLogTime":[{"Reporter":"uid:1","Hours":1.5},{"Reporter":"uid:2","Hours":1}]}`+"\n"+
+		`{"Key":"QC-3","Status":"open","Votes":"","Ratio":"","Project":"=LEFT(A3,2)","JiraLogTime":[]}`+"\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[1].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, `{"Key":"QC-1","Component":"<api>"}`+"\n"+`{"Key":"QC-3","Component":"ui"}`+"\n", string(data))
+
+	// the nested rows are not supported in the other formats
+	config.Output = filepath.Join(dir, "output.csv")
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(input))
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+}
+
+func TestPipelineSQLiteOutput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.db")
+	config := &CSVConvertorConfig{
+		Output:    output,
+		SheetName: "issues",
+		Fields: ParseFieldConfigs(
+			"Issue key,Key,12",
+			"Votes,Votes,8,int",
+			",Project,15,func,LEFT(A{row},2)",
+			"Spent,Spent,12,sec2day",
+			"Log Work,,0,subfile,JiraLogTime",
+		),
+		Subfiles: []*SubFile{{
+			Name:      "JiraLogTime",
+			SheetName: "time spent",
+			Output:    output,
+			TableMode: TableModeAppend,
+			Fields:    ParseFieldConfigs("Issue key,Key,12", "value,Hours,8,sec2hour"),
+		}},
+	}
+	input := "Issue key,Votes,Spent,Log Work\n" +
+		"QC-1,3,90000,;05/Jan/21 8:45 AM;uid:1;5400\n" +
+		"QC-2,,,;06/Jan/21 8:45 AM;uid:2;3600\n"
+
+	// the subfile table is appended and the main table is replaced in the second run
+	for i := 0; i < 2; i++ {
+		pipeline, err := NewPipeline(config, nil)
+		tt.Nil(t, err)
+		tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	}
+
+	db, err := sql.Open("sqlite", output)
+	tt.Nil(t, err)
+	defer db.Close()
+
+	query := func(statement string) []string {
+		rows, err := db.Query(statement)
+		tt.Nil(t, err)
+		defer rows.Close()
+		result := make([]string, 0)
+		for rows.Next() {
+			var key, value, kind string
+			tt.Nil(t, rows.Scan(&key, &value, &kind))
+			result = append(result, key+"|"+value+"|"+kind)
+		}
+		return result
+	}
+	tt.Equal(t, []string{"QC-1|3|integer", "QC-2||null"}, query(`SELECT Key, IFNULL(Votes, ''), TYPEOF(Votes) FROM issues`))
+	tt.Equal(t, []string{"QC-1|=LEFT(A2,2)|text", "QC-2|=LEFT(A3,2)|text"}, query(`SELECT Key, Project, TYPEOF(Project) FROM issues`))
+	// sec2day writes the text of the days, hours and minutes
+	tt.Equal(t, []string{"QC-1|01d 01h 00s|text", "QC-2||text"}, query(`SELECT Key, Spent, TYPEOF(Spent) FROM issues`))
+	tt.Equal(t, []string{"QC-1|1.5|real", "QC-2|1|real", "QC-1|1.5|real", "QC-2|1|real"},
+		query(`SELECT Key, Hours, TYPEOF(Hours) FROM "time spent"`))
+}
+
+func TestPipelineWorkbookInput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.xlsx")
+	writeLookupFile(t, input, "Issues", [][]string{
+		{"Issue export"},
+		{"Issue key", "Votes", "Team", "Log Work", "Log Work"},
+		{"QC-1", "3", "red", ";05/Jan/21 8:45 AM;uid:1;5400", ";06/Jan/21 8:45 AM;uid:2;3600"},
+		{},
+		{"QC-2", "1", "blue"},
+		{"QC-3"},
+	})
+
+	output := filepath.Join(dir, "output.xlsx")
+	config := &CSVConvertorConfig{
+		Input:      InputFiles{input},
+		InputSheet: "Issues",
+		HeaderRow:  2,
+		Output:     output,
+		SheetName:  "data",
+		Fields: ParseFieldConfigs(
+			"Issue key,Key,12",
+			"Votes,Votes,8,int",
+			"Team,Team,8",
+			"Log Work,,0,subfile,JiraLogTime",
+		),
+		Subfiles: []*SubFile{{
+			Name:      "JiraLogTime",
+			SheetName: "Time Spent",
+			Output:    output,
+			Fields:    ParseFieldConfigs("Issue key,Key,12", "value,Hours,8,sec2hour"),
+		}},
+		Filters: []*Filter{{Field: "Team", Values: []string{"red", ""}}},
+	}
+
+	inf, err := OpenInput(input)
+	tt.Nil(t, err)
+	defer inf.Close()
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), inf))
+
+	tt.Equal(t, [][]string{{"Key", "Votes", "Team"}, {"QC-1", "3", "red"}, {"QC-3"}}, readSheet(t, output, "data"))
+	tt.Equal(t, [][]string{{"Key", "Hours"}, {"QC-1", "1.5"}, {"QC-1", "1"}}, readSheet(t, output, "Time Spent"))
+
+	header, err := ReadSheetHeader(mustOpen(t, input), "Issues", 2)
+	tt.Nil(t, err)
+	tt.Equal(t, []string{"Issue key", "Votes", "Team", "Log Work", "Log Work"}, header)
+
+	config.InputSheet = "Missing"
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), mustOpen(t, input))
+	tt.True(t, errors.Is(err, ErrInputRead))
+}
+
+func TestPipelineInputOptions(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.csv")
+	config := &CSVConvertorConfig{
+		Output: output,
+		InputOptions: InputOptions{
+			Delimiter:        ";",
+			Comment:          "#",
+			LazyQuotes:       true,
+			TrimLeadingSpace: true,
+			VariableFields:   true,
+			SkipRows:         2,
+		},
+		Fields: ParseFieldConfigs("ID,ID,10,int", "Name,Name,20", "Team,Team,10"),
+	}
+	input := "Export of 2021-01-05; \"all\" issues\n\n" +
+		"ID;Name;Team\n" +
+		"# comment line\n" +
+		"1; alice;red\n" +
+		"2;bob \"the builder\"\n" +
+		"3;carol;blue;extra\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(output)
+	tt.Nil(t, err)
+	tt.Equal(t, "ID,Name,Team\n1,alice,red\n2,\"bob \"\"the builder\"\"\",\n3,carol,blue\n", string(data))
+
+	header, err := ReadHeader(strings.NewReader(input), config.InputOptions)
+	tt.Nil(t, err)
+	tt.Equal(t, []string{"ID", "Name", "Team"}, header)
+
+	// the records with a different field count fail by default
+	config.InputOptions.VariableFields = false
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(input))
+	tt.True(t, errors.Is(err, ErrInputRead))
+
+	config.InputOptions.Delimiter = ";;"
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(input))
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+}
+
+func TestPipelineInputEncoding(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.csv")
+	config := &CSVConvertorConfig{
+		Output: output,
+		Fields: ParseFieldConfigs("编号,ID,10,int", "名前,Name,20"),
+	}
+	input := "编号,名前\n1,张三\n2,山田\n"
+	expected := "ID,Name\n1,张三\n2,山田\n"
+
+	var testData = []struct {
+		encoding string
+		content  encoding.Encoding
+	}{
+		{"gbk", simplifiedchinese.GBK},
+		{"GB18030", simplifiedchinese.GB18030},
+		{"", unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)}, // detected from the BOM
+		{"utf-16be", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+	}
+	for _, data := range testData {
+		content, err := data.content.NewEncoder().String(input)
+		tt.Nil(t, err)
+
+		config.Encoding = data.encoding
+		pipeline, err := NewPipeline(config, nil)
+		tt.Nil(t, err)
+		tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(content)))
+		result, err := os.ReadFile(output)
+		tt.Nil(t, err)
+		tt.Equal(t, expected, string(result))
+	}
+
+	config.Encoding = "ebcdic"
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(input))
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+}
+
+func TestPipelineInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"export-1.csv": "Issue key,Log Work\nQC-1,;05/Jan/21 8:45 AM;uid:1;5400\n",
+		"export-2.csv": "Issue key,Votes,Log Work,Log Work\nQC-2,3,;06/Jan/21 8:45 AM;uid:2;3600,;07/Jan/21 8:45 AM;uid:3;1800\n",
+		"extra.csv":    "Votes,Issue key\n1,QC-3\n",
+	}
+	for name, content := range files {
+		tt.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	output := filepath.Join(dir, "output.xlsx")
+	configFile := filepath.Join(dir, "config.yaml")
+	tt.Nil(t, os.WriteFile(configFile, []byte(`
+input:
+    - '`+filepath.Join(dir, "export-*.csv")+`'
+    - '`+filepath.Join(dir, "extra.csv")+`'
+output: '`+output+`'
+sheetName: data
+sourceFileField: Source
+fields:
+    - "Issue key,Key,12"
+    - "Votes,Votes,8,int"
+    - "Log Work,,0,subfile,JiraLogTime"
+subfile:
+    - name: JiraLogTime
+      sheetName: Time Spent
+      output: '`+output+`'
+      fields:
+          - "Issue key,Key,12"
+          - "Source,Source,20"
+          - "value,Hours,8,sec2hour"
+`), 0644))
+	config, err := ReadConfig(configFile)
+	tt.Nil(t, err)
+	tt.Equal(t, 2, len(config.Input))
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.RunFiles(context.Background()))
+
+	export1, export2, extra := filepath.Join(dir, "export-1.csv"), filepath.Join(dir, "export-2.csv"), filepath.Join(dir, "extra.csv")
+	tt.Equal(t, [][]string{{"Key", "Votes", "Source"}, {"QC-1", "", export1}, {"QC-2", "3", export2}, {"QC-3", "1", extra}},
+		readSheet(t, output, "data"))
+	tt.Equal(t, [][]string{{"Key", "Source", "Hours"}, {"QC-1", export1, "1.5"}, {"QC-2", export2, "1"}, {"QC-2", export2, "0.5"}},
+		readSheet(t, output, "Time Spent"))
+
+	config.Input = InputFiles{filepath.Join(dir, "missing-*.csv")}
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.RunFiles(context.Background())
+	tt.True(t, errors.Is(err, ErrInputRead))
+
+	// the source column doesn't count as a matched header field
+	other := filepath.Join(dir, "other.csv")
+	tt.Nil(t, os.WriteFile(other, []byte("Foo,Bar\n1,2\n"), 0644))
+	config.Input = InputFiles{other}
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.RunFiles(context.Background())
+	tt.True(t, errors.Is(err, ErrInputRead))
+	tt.Equal(t, "unable to read input (file: "+other+", row: 1): unable to found matched header fields", err.Error())
+
+	// the source field of the JSON input is not read as a JSON path
+	jsonl := filepath.Join(dir, "export.jsonl")
+	tt.Nil(t, os.WriteFile(jsonl, []byte(`{"key":"QC-4"}`+"\n"), 0644))
+	jsonOutput := filepath.Join(dir, "json.csv")
+	pipeline, err = NewPipeline(&CSVConvertorConfig{
+		Input:           InputFiles{jsonl},
+		Output:          jsonOutput,
+		SourceFileField: "Src",
+		Fields:          ParseFieldConfigs("key,Key,12"),
+	}, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.RunFiles(context.Background()))
+	data, err := os.ReadFile(jsonOutput)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Src\nQC-4,"+jsonl+"\n", string(data))
+}
+
+func TestPipelineCompressedInput(t *testing.T) {
+	dir := t.TempDir()
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, err := gw.Write([]byte(testCSV))
+	tt.Nil(t, err)
+	tt.Nil(t, gw.Close())
+	tt.Nil(t, os.WriteFile(filepath.Join(dir, "input.csv.gz"), gzipped.Bytes(), 0644))
+
+	encoder, err := zstd.NewWriter(nil)
+	tt.Nil(t, err)
+	// detected from the magic bytes
+	tt.Nil(t, os.WriteFile(filepath.Join(dir, "input-zstd.csv"), encoder.EncodeAll([]byte(testCSV), nil), 0644))
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for _, name := range []string{"a.csv", "readme.md", "b.csv"} {
+		w, err := zw.Create(name)
+		tt.Nil(t, err)
+		_, err = w.Write([]byte(testCSV))
+		tt.Nil(t, err)
+	}
+	tt.Nil(t, zw.Close())
+	tt.Nil(t, os.WriteFile(filepath.Join(dir, "input.zip"), zipped.Bytes(), 0644))
+
+	output := filepath.Join(dir, "output.csv")
+	config := &CSVConvertorConfig{
+		Output:          output,
+		SourceFileField: "Source",
+		Fields:          ParseFieldConfigs("ID,ID,10,int", "Name,Name,20"),
+		Filters:         []*Filter{{Field: "Name", Values: []string{"bob"}}},
+	}
+	var testData = []struct {
+		input    string
+		member   string
+		expected string
+	}{
+		{"input.csv.gz", "", "ID,Name,Source\n2,bob,{dir}/input.csv.gz\n"},
+		{"input-zstd.csv", "", "ID,Name,Source\n2,bob,{dir}/input-zstd.csv\n"},
+		{"input.zip", "", "ID,Name,Source\n2,bob,{dir}/input.zip/a.csv\n2,bob,{dir}/input.zip/b.csv\n"},
+		{"input.zip", "b.*", "ID,Name,Source\n2,bob,{dir}/input.zip/b.csv\n"},
+	}
+	for _, data := range testData {
+		config.Input = InputFiles{filepath.Join(dir, data.input)}
+		config.InputMember = data.member
+		pipeline, err := NewPipeline(config, nil)
+		tt.Nil(t, err)
+		tt.Nil(t, pipeline.RunFiles(context.Background()))
+		result, err := os.ReadFile(output)
+		tt.Nil(t, err)
+		tt.Equal(t, strings.ReplaceAll(data.expected, "{dir}", dir), string(result))
+	}
+
+	// the zip archive in a reader is read into memory
+	config.InputMember = ""
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), bytes.NewReader(zipped.Bytes())))
+
+	config.InputMember = "c.csv"
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.RunFiles(context.Background())
+	tt.True(t, errors.Is(err, ErrInputRead))
+
+	// the header of a sample is read from the decompressed content, or the first member of the zip archive
+	for input, member := range map[string]string{"input.csv.gz": "input.csv.gz", "input.zip": "input.zip/a.csv"} {
+		content, name, err := OpenInputMember(filepath.Join(dir, input), "")
+		tt.Nil(t, err)
+		tt.Equal(t, filepath.Join(dir, member), name)
+		header, err := ReadHeader(content, InputOptions{})
+		tt.Nil(t, err)
+		tt.Equal(t, []string{"ID", "Name", "Team"}, header)
+		tt.Nil(t, content.Close())
+	}
+	_, _, err = OpenInputMember(filepath.Join(dir, "input.zip"), "c.csv")
+	tt.True(t, errors.Is(err, ErrInputRead))
+}
+
+func TestPipelineHeaderMatch(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.csv")
+	config := &CSVConvertorConfig{
+		Output:      output,
+		HeaderMatch: HeaderMatchCaseInsensitive,
+		Fields: []*FieldConfig{
+			{Input: "Issue key", Output: "Key", Width: 12},
+			{Input: "Fix Version/s", Output: "Version", Width: 12, Aliases: []string{"Fix versions"}},
+			{Input: "Team", Output: "Team", Width: 12, Regex: `^Custom field \(Team.*\)$`},
+			{Input: "Log Work", Type: "subfile", Params: []string{"JiraLogTime"}},
+		},
+		Subfiles: []*SubFile{{
+			Name:   "JiraLogTime",
+			Output: filepath.Join(t.TempDir(), "time.csv"),
+			Fields: ParseFieldConfigs("Issue key,Key,12", "value,Hours,8,sec2hour"),
+		}},
+	}
+	// the first matching column is taken
+	input := " ISSUE KEY ,fix versions,Custom field (Team),Custom field (Team Area),log work,Log Work\n" +
+		"QC-1,1.0,red,blue,;05/Jan/21 8:45 AM;uid:1;5400,;06/Jan/21 8:45 AM;uid:2;3600\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Version,Team\nQC-1,1.0,red\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[0].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Hours\nQC-1,1.5\nQC-1,1\n", string(data))
+
+	// the exact match by default
+	config.HeaderMatch = ""
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err = os.ReadFile(output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Version,Team\n,,red\n", string(data))
+
+	config.HeaderMatch = "fuzzy"
+	_, err = NewPipeline(config, nil)
+	tt.True(t, errors.Is(err, ErrConfigInvalid))
+}
+
+func TestPipelineHeaderReport(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.csv")
+	config := &CSVConvertorConfig{
+		Input:  InputFiles{"input.csv"},
+		Output: output,
+		Fields: []*FieldConfig{
+			{Input: "ID", Output: "ID", Width: 10},
+			{Input: "Name", Output: "Name", Width: 20, Required: true},
+			{Input: "Email", Output: "Email", Width: 20},
+		},
+	}
+	input := "ID,Team,Full Name\n1,red,alice\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	tt.Equal(t, []HeaderReport{{
+		File:    "input.csv",
+		Matched: []string{"ID"},
+		Missing: []string{"Name", "Email"},
+		Unused:  []string{"Team", "Full Name"},
+	}}, pipeline.HeaderReports())
+
+	config.StrictHeader = true
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(input))
+	tt.True(t, errors.Is(err, ErrInputRead))
+	tt.Equal(t, "unable to read input (file: input.csv, row: 1, field: Name): the required input field is not found in the header", err.Error())
+
+	// the missing fields which are not required are still accepted
+	config.Fields[1].Aliases = []string{"Full Name"}
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(output)
+	tt.Nil(t, err)
+	tt.Equal(t, "ID,Name,Email\n1,alice,\n", string(data))
+}
+
+func TestPipelineHeaderless(t *testing.T) {
+	dir := t.TempDir()
+	hasHeader := false
+	config := &CSVConvertorConfig{
+		HasHeader: &hasHeader,
+		Output:    filepath.Join(dir, "output.csv"),
+		Fields: ParseFieldConfigs(
+			"#1,Key,12",
+			"$b,Votes,8,int",
+			"#4,,0,subfile,JiraLogTime",
+		),
+		Subfiles: []*SubFile{{
+			Name:   "JiraLogTime",
+			Output: filepath.Join(dir, "time.csv"),
+			Fields: ParseFieldConfigs("#1,Key,12", "$C,Team,8", "value,Hours,8,sec2hour"),
+		}},
+	}
+	input := "\ufeffQC-1,3,red,;05/Jan/21 8:45 AM;uid:1;5400\nQC-2,1,blue,;06/Jan/21 8:45 AM;uid:2;3600\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Votes\nQC-1,3\nQC-2,1\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[0].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Team,Hours\nQC-1,red,1.5\nQC-2,blue,1\n", string(data))
+
+	// the positions also refer to the columns with a header
+	hasHeader = true
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err = os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Votes\nQC-2,1\n", string(data))
+
+	tt.Equal(t, 0, len(Validate(config, []string{"Key", "Votes", "Team", "Log Work"})))
+}
+
+func TestPipelineExplode(t *testing.T) {
+	dir := t.TempDir()
+	config := &CSVConvertorConfig{
+		Output: filepath.Join(dir, "output.csv"),
+		Fields: []*FieldConfig{
+			{Input: "Issue key", Output: "Key", Width: 12},
+			{Input: "CVE", Type: "explode", Params: []string{"cves"}},
+			{Input: "Labels", Type: "explode", Params: []string{"labels", ","}},
+		},
+		Subfiles: []*SubFile{
+			{
+				Name:   "cves",
+				Output: filepath.Join(dir, "cves.csv"),
+				Fields: ParseFieldConfigs("Issue key,Key,12", "value,CVE,16"),
+			},
+			{
+				Name:      "labels",
+				Output:    filepath.Join(dir, "labels.csv"),
+				Separator: ":",
+				Fields:    ParseFieldConfigs("Issue key,Key,12", "value[1],Label,16", "value[2],Scope,8"),
+			},
+		},
+	}
+	input := "Issue key,CVE,Labels,Labels\n" +
+		"QC-1,CVE-2021-1;CVE-2021-2 ,\"api:team, ui\",db\n" +
+		"QC-2,,,\n" +
+		"QC-3,CVE-2022-9,ops,\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key\nQC-1\nQC-2\nQC-3\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[0].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,CVE\nQC-1,CVE-2021-1\nQC-1,CVE-2021-2\nQC-3,CVE-2022-9\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[1].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Label,Scope\nQC-1,api,team\nQC-1,ui,\nQC-1,db,\nQC-3,ops,\n", string(data))
+}
+
+func TestPipelineSubfileConverters(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "dict.xlsx")
+	writeLookupFile(t, dict, "Team", [][]string{{"Name", "Team"}, {"alice", "red"}, {"bob", "blue"}, {"QC", "core"}})
+	config := &CSVConvertorConfig{
+		Output: filepath.Join(dir, "output.csv"),
+		Fields: ParseFieldConfigs(
+			"Issue key,Key,12",
+			"Log Work,,0,subfile,JiraLogTime",
+			"Project,Project,8",
+			// the lookup refers to the field after the subfile field, which takes no output column
+			",Team,8,lookup,Project,Team,2",
+		),
+		Subfiles: []*SubFile{
+			{
+				Name:      "JiraLogTime",
+				Output:    filepath.Join(dir, "worklog.csv"),
+				Separator: ";",
+				Fields: ParseFieldConfigs(
+					"Issue key,Key,12",
+					"value[-3],,0",
+					"value[-2],Reporter,12",
+					",Team,8,lookup,Reporter,Team,2",
+					"value[-1],Hours,8,sec2hour",
+					",Check,8,func,D{row}>1",
+					",Source,8,constant,jira",
+				),
+			},
+		},
+		Lookups: []*Lookup{{Name: "Team", FileName: dict, SheetName: "Team"}},
+	}
+	input := "Issue key,Project,Log Work,Log Work\n" +
+		"QC-1,QC,;05/Jan/21 8:45 AM;alice;5400,;06/Jan/21 9:00 AM;bob;1800\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Project,Team\nQC-1,QC,core\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[0].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Reporter,Team,Hours,Check,Source\n"+
+		"QC-1,alice,red,1.5,=D2>1,jira\n"+
+		"QC-1,bob,blue,0.5,=D3>1,jira\n", string(data))
+}
+
+func TestPipelineSubfileOutputName(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "dict.xlsx")
+	writeLookupFile(t, dict, "Owner", [][]string{{"Host", "Owner"}, {"db1", "alice"}})
+	config := &CSVConvertorConfig{
+		Output: filepath.Join(dir, "output.csv"),
+		Fields: ParseFieldConfigs(
+			"Host,Host,20",
+			"Sev,Sev,5,int",
+			// the output name of the explode field takes no output column
+			"Ports,-,0,explode,ports",
+			"Status,Status,8",
+			",Owner,8,lookup,Host,Owner,2",
+		),
+		Filters:  []*Filter{{Field: "Status", Values: []string{"open"}}},
+		Subfiles: []*SubFile{{Name: "ports", Output: filepath.Join(dir, "ports.csv"), Fields: ParseFieldConfigs("@Host,Host,20", "@Owner,Owner,8", "value,Port,8")}},
+		Lookups:  []*Lookup{{Name: "Owner", FileName: dict, SheetName: "Owner"}},
+	}
+	input := "Host,Sev,Ports,Status\n" +
+		"db1,3,443;8443,open\n" +
+		"web1,1,80,closed\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Host,Sev,Status,Owner\ndb1,3,open,alice\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[0].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Host,Owner,Port\ndb1,alice,443\ndb1,alice,8443\nweb1,,80\n", string(data))
+}
+
+func TestPipelineSubfileMasterFields(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "dict.xlsx")
+	writeLookupFile(t, dict, "HostType", [][]string{{"Host", "Type"}, {"db1", "Database"}})
+	config := &CSVConvertorConfig{
+		Output: filepath.Join(dir, "output.csv"),
+		Fields: ParseFieldConfigs(
+			"Host,Host,12",
+			",Server Type,12,lookup,Host,HostType,2",
+			"Time Spent,Spent,8,sec2hour",
+			"Ports,,0,explode,ports",
+		),
+		Subfiles: []*SubFile{
+			{
+				Name:   "ports",
+				Output: filepath.Join(dir, "ports.csv"),
+				Fields: ParseFieldConfigs("Host,Host,12", "@Server Type,Type,12", "@Spent,Spent,8,float", "value,Port,8,int"),
+			},
+		},
+		Lookups: []*Lookup{{Name: "HostType", FileName: dict, SheetName: "HostType"}},
+	}
+	input := "Host,Time Spent,Ports\n" +
+		"db1,5400,443;8443\n" +
+		"web1,,80\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Host,Server Type,Spent\ndb1,Database,1.5\nweb1,,\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[0].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Host,Type,Spent,Port\ndb1,Database,1.5,443\ndb1,Database,1.5,8443\nweb1,,,80\n", string(data))
+
+	config.Subfiles[0].Fields = ParseFieldConfigs("@Owner,Owner,12", "value,Port,8")
+	_, err = NewPipeline(config, nil)
+	tt.Equal(t, "invalid config (field: @Owner, path: subfile.0): subfile ports: the referenced master field [Owner] is not defined", err.Error())
+}
+
+func TestPipelineSubfileFilters(t *testing.T) {
+	dir := t.TempDir()
+	config := &CSVConvertorConfig{
+		Output: filepath.Join(dir, "output.csv"),
+		Fields: ParseFieldConfigs(
+			"Issue key,Key,12",
+			"Status,Status,8",
+			"Worklog,,0,explode,worklog",
+			"Labels,,0,explode,labels",
+		),
+		Filters: []*Filter{{Field: "Status", Values: []string{"open"}}},
+		Subfiles: []*SubFile{
+			{
+				Name:         "worklog",
+				Output:       filepath.Join(dir, "worklog.csv"),
+				Separator:    ":",
+				Fields:       ParseFieldConfigs("value[1],Month,8", "Issue key,Key,12", "value[2],Hours,8,float"),
+				Filters:      []*Filter{{Field: "Month", Values: []string{"2021-02"}}},
+				FollowFilter: true,
+			},
+			{
+				Name:   "labels",
+				Output: filepath.Join(dir, "labels.csv"),
+				Fields: ParseFieldConfigs("Issue key,Key,12", "value,Label,16"),
+			},
+		},
+	}
+	input := "Issue key,Status,Worklog,Labels\n" +
+		"QC-1,open,2021-01:2;2021-02:3,api\n" +
+		"QC-2,closed,2021-02:4,db\n" +
+		"QC-3,open,2021-02:1,\n"
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	data, err := os.ReadFile(config.Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Status\nQC-1,open\nQC-3,open\n", string(data))
+	data, err = os.ReadFile(config.Subfiles[0].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Month,Key,Hours\n2021-02,QC-1,3\n2021-02,QC-3,1\n", string(data))
+	// the subfile rows are kept regardless of the main filters unless followFilter is set
+	data, err = os.ReadFile(config.Subfiles[1].Output)
+	tt.Nil(t, err)
+	tt.Equal(t, "Key,Label\nQC-1,api\nQC-2,db\n", string(data))
+}
+
+func mustOpen(t *testing.T, fileName string) *os.File {
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("open %s: %s", fileName, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestPipelineJSONInput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.xlsx")
+	config := &CSVConvertorConfig{
+		Input:     InputFiles{filepath.Join(dir, "input.json")},
+		Output:    output,
+		SheetName: "data",
+		Fields: ParseFieldConfigs(
+			"key,Key,12",
+			"fields.assignee.displayName,Assignee,20",
+			"fields.votes,Votes,8,int",
+			"fields.components.name,,0,subfile,components",
+		),
+		Subfiles: []*SubFile{{
+			Name:      "components",
+			SheetName: "Components",
+			Output:    output,
+			Fields:    ParseFieldConfigs("key,Key,12", "value,Component,12"),
+		}},
+	}
+	input := `[
+		{"key": "QC-1", "fields": {"assignee": {"displayName": "alice"}, "votes": 3,
+			"components": [{"name": "api"}, {"name": "ui"}]}},
+		{"key": "QC-2", "fields": {"assignee": null, "votes": 1, "components": []}},
+		{"key": "QC-3", "fields": {"votes": 10, "components": [{"name": "db"}]}}
+	]`
+
+	pipeline, err := NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(input)))
+	tt.Equal(t, [][]string{{"Key", "Assignee", "Votes"}, {"QC-1", "alice", "3"}, {"QC-2", "", "1"}, {"QC-3", "", "10"}},
+		readSheet(t, output, "data"))
+	tt.Equal(t, [][]string{{"Key", "Component"}, {"QC-1", "api"}, {"QC-1", "ui"}, {"QC-3", "db"}},
+		readSheet(t, output, "Components"))
+
+	// the same objects in JSON Lines
+	config.Input = InputFiles{filepath.Join(dir, "input.jsonl")}
+	lines := `{"key": "QC-1", "fields": {"components": [{"name": "api"}]}}` + "\n\n" +
+		`{"key": "QC-2", "fields": {"assignee": {"displayName": "bob"}, "components": [{"name": "ui"}, {"name": "db"}]}}` + "\n"
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	tt.Nil(t, pipeline.Run(context.Background(), strings.NewReader(lines)))
+	tt.Equal(t, [][]string{{"Key", "Assignee", "Votes"}, {"QC-1"}, {"QC-2", "bob"}}, readSheet(t, output, "data"))
+	tt.Equal(t, [][]string{{"Key", "Component"}, {"QC-1", "api"}, {"QC-2", "ui"}, {"QC-2", "db"}},
+		readSheet(t, output, "Components"))
+
+	config.Input = InputFiles{filepath.Join(dir, "input.json")}
+	pipeline, err = NewPipeline(config, nil)
+	tt.Nil(t, err)
+	err = pipeline.Run(context.Background(), strings.NewReader(`{"key": "QC-1"}`))
+	tt.True(t, errors.Is(err, ErrInputRead))
+}
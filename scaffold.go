@@ -0,0 +1,181 @@
+package qc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ScaffoldConfig reads the header and up to sampleRows records of the csv content in input, and returns
+// a ready-to-edit config listing every column. The int and float types are guessed from the sampled values,
+// and the repeated headers are defined as subfile fields with the matching subfile stubs.
+// The inputName is the input file path written in the config. No record is sampled if sampleRows is not positive.
+func ScaffoldConfig(input io.Reader, inputName string, sampleRows int) ([]byte, error) {
+	if sampleRows < 0 {
+		sampleRows = 0
+	}
+	r := csv.NewReader(input)
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, &Error{Kind: ErrInputRead, File: inputName, Row: 1, Err: err}
+	}
+	trimBOM(header)
+
+	samples := make([][]string, 0, sampleRows)
+	for len(samples) < sampleRows {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &Error{Kind: ErrInputRead, File: inputName, Row: len(samples) + 2, Err: err}
+		}
+		samples = append(samples, record)
+	}
+
+	count := make(map[string]int)
+	for _, iter := range header {
+		count[iter]++
+	}
+
+	base := inputName
+	if ext := filepath.Ext(base); inputExt(base) != strings.ToLower(ext) {
+		// skip the extension of the gzip or zstd compression, e.g. export.csv.gz
+		base = strings.TrimSuffix(base, ext)
+	}
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	if base == "" {
+		base = "output"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "input: %s\n", yamlQuote(inputName))
+	fmt.Fprintf(&buf, "output: %s\n", yamlQuote(base+".xlsx"))
+	fmt.Fprintf(&buf, "sheetName: %s\n", yamlQuote("rawdata"))
+	fmt.Fprintf(&buf, "fields: \n")
+
+	repeated := make([]string, 0)
+	written := make(map[string]bool)
+	for id, iter := range header {
+		if iter == "" || written[iter] {
+			continue
+		}
+		written[iter] = true
+
+		if count[iter] > 1 {
+			repeated = append(repeated, iter)
+			writeFieldConfig(&buf, "    ", iter, "", 0, "subfile", subfileName(iter))
+			continue
+		}
+		writeFieldConfig(&buf, "    ", iter, iter, defaultFieldWidth, guessFieldType(samples, id))
+	}
+
+	if len(repeated) > 0 {
+		// the first column is used as the key of the subfile records
+		key := header[0]
+		fmt.Fprintf(&buf, "subfile: \n")
+		for _, iter := range repeated {
+			name := subfileName(iter)
+			fmt.Fprintf(&buf, "    - name: %s\n", yamlQuote(name))
+			fmt.Fprintf(&buf, "      sheetName: %s\n", yamlQuote(sheetName(name)))
+			fmt.Fprintf(&buf, "      output: %s\n", yamlQuote(base+"-"+name+".xlsx"))
+			fmt.Fprintf(&buf, "      fields: \n")
+			if key != iter {
+				writeFieldConfig(&buf, "            ", key, key, defaultFieldWidth, "")
+			}
+			writeFieldConfig(&buf, "            ", "value", iter, defaultFieldWidth, "")
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeFieldConfig writes a field definition in the string form, or in the map form if the names include `,`
+func writeFieldConfig(buf *bytes.Buffer, indent string, input, output string, width int, fieldType string, params ...string) {
+	if !strings.Contains(input, ",") && !strings.Contains(output, ",") {
+		fields := []string{input, output, strconv.Itoa(width)}
+		if fieldType != "" {
+			fields = append(fields, fieldType)
+			fields = append(fields, params...)
+		}
+		fmt.Fprintf(buf, "%s- %s\n", indent, yamlQuote(strings.Join(fields, ",")))
+		return
+	}
+
+	fmt.Fprintf(buf, "%s- input: %s\n", indent, yamlQuote(input))
+	fmt.Fprintf(buf, "%s  output: %s\n", indent, yamlQuote(output))
+	fmt.Fprintf(buf, "%s  width: %d\n", indent, width)
+	if fieldType != "" {
+		fmt.Fprintf(buf, "%s  type: %s\n", indent, fieldType)
+	}
+	if len(params) > 0 {
+		quoted := make([]string, 0, len(params))
+		for _, iter := range params {
+			quoted = append(quoted, yamlQuote(iter))
+		}
+		fmt.Fprintf(buf, "%s  params: [%s]\n", indent, strings.Join(quoted, ", "))
+	}
+}
+
+// guessFieldType returns int or float if all the non-empty sampled values in the column are numbers
+func guessFieldType(samples [][]string, column int) string {
+	result := ""
+	for _, record := range samples {
+		if column >= len(record) {
+			continue
+		}
+		value := strings.TrimSpace(record[column])
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(value); err == nil {
+			if result == "" {
+				result = "int"
+			}
+			continue
+		}
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			result = "float"
+			continue
+		}
+		return ""
+	}
+	return result
+}
+
+// subfileName converts a header name into a subfile name, e.g. "Log Work" into "LogWork"
+func subfileName(header string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, header)
+	if name == "" {
+		name = "subfile"
+	}
+	return name
+}
+
+// sheetName truncates the name to the 31 characters allowed in the Excel sheet names
+func sheetName(name string) string {
+	runes := []rune(name)
+	if len(runes) > 31 {
+		return string(runes[:31])
+	}
+	return name
+}
+
+// yamlQuote returns the value as a double-quoted YAML string
+func yamlQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,73 @@
+package qc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestScaffoldConfig(t *testing.T) {
+	input := "\ufeffIssue key,Time Spent,Rate,\"Custom field (Team, Area)\",Log Work,Log Work\n" +
+		"QC-1,3600,1.5,red,;05/Jan/21 8:45 AM;uid:1;14400,\n" +
+		"QC-2,,2,blue,,\n"
+
+	content, err := ScaffoldConfig(strings.NewReader(input), "data/export.csv", 10)
+	tt.Nil(t, err)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	tt.Nil(t, os.WriteFile(path, content, 0644))
+	config, err := ReadConfig(path)
+	tt.Nil(t, err)
+
+	tt.Equal(t, InputFiles{"data/export.csv"}, config.Input)
+	tt.Equal(t, "data/export.xlsx", config.Output)
+	tt.Equal(t, []FieldConfig{
+		{Input: "Issue key", Output: "Issue key", Width: 20},
+		{Input: "Time Spent", Output: "Time Spent", Width: 20, Type: "int"},
+		{Input: "Rate", Output: "Rate", Width: 20, Type: "float"},
+		{Input: "Custom field (Team, Area)", Output: "Custom field (Team, Area)", Width: 20},
+		{Input: "Log Work", Width: 0, Type: "subfile", Params: []string{"LogWork"}},
+	}, stripDefinitions(config.Fields))
+
+	tt.Equal(t, 1, len(config.Subfiles))
+	tt.Equal(t, "LogWork", config.Subfiles[0].Name)
+	tt.Equal(t, "data/export-LogWork.xlsx", config.Subfiles[0].Output)
+	tt.Equal(t, []FieldConfig{
+		{Input: "Issue key", Output: "Issue key", Width: 20},
+		{Input: "value", Output: "Log Work", Width: 20},
+	}, stripDefinitions(config.Subfiles[0].Fields))
+
+	tt.Equal(t, 0, len(Validate(config, nil)))
+
+	// no type is guessed without the sampled records
+	content, err = ScaffoldConfig(strings.NewReader(input), "data/export.csv", -1)
+	tt.Nil(t, err)
+	tt.Nil(t, os.WriteFile(path, content, 0644))
+	config, err = ReadConfig(path)
+	tt.Nil(t, err)
+	tt.Equal(t, "", config.Fields[1].Type)
+	tt.Equal(t, "", config.Fields[2].Type)
+
+	// the compression extension is not kept in the output names
+	content, err = ScaffoldConfig(strings.NewReader(input), "data/export.csv.gz", 10)
+	tt.Nil(t, err)
+	tt.Nil(t, os.WriteFile(path, content, 0644))
+	config, err = ReadConfig(path)
+	tt.Nil(t, err)
+	tt.Equal(t, "data/export.xlsx", config.Output)
+	tt.Equal(t, "data/export-LogWork.xlsx", config.Subfiles[0].Output)
+}
+
+// stripDefinitions returns the parsed field configs without the original definitions
+func stripDefinitions(fieldConfigs []*FieldConfig) []FieldConfig {
+	result := make([]FieldConfig, 0, len(fieldConfigs))
+	for _, iter := range fieldConfigs {
+		fc := *iter
+		fc.definition = ""
+		result = append(result, fc)
+	}
+	return result
+}
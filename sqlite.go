@@ -0,0 +1,180 @@
+package qc
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	// the pure Go sqlite driver, which builds without cgo
+	_ "modernc.org/sqlite"
+)
+
+// the values of the tableMode setting, which decides how the existing table is handled in the sqlite output
+const (
+	TableModeReplace = "replace" // drop the existing table and create it again
+	TableModeAppend  = "append"  // keep the existing table and add the records into it
+)
+
+func checkTableMode(tableMode string) error {
+	switch tableMode {
+	case "", TableModeReplace, TableModeAppend:
+		return nil
+	}
+	return fmt.Errorf("unknown tableMode option %s, should be %s or %s", tableMode, TableModeReplace, TableModeAppend)
+}
+
+// sqliteColumnType returns the column type of a field, based on the value produced by its converter
+func sqliteColumnType(field *Field) string {
+	switch field.converterType {
+	case ConverterTypeInt:
+		return "INTEGER"
+	case ConverterTypeFloat, ConverterTypeSec2hour:
+		return "REAL"
+	}
+	return "TEXT"
+}
+
+// sqliteQuote returns the name as a quoted identifier
+func sqliteQuote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// database is a sqlite output file, the tables with the same output file name share a single database.
+// All the records are written in a transaction, which is committed when saved.
+type database struct {
+	fileName string
+	db       *sql.DB
+	tx       *sql.Tx
+	tables   map[string]bool
+	created  bool // the file is newly created thus removed if not saved
+	done     bool
+	saved    bool
+}
+
+func openDatabase(fileName string) (*database, error) {
+	created := !IsFile(fileName)
+	db, err := sql.Open("sqlite", fileName)
+	if err != nil {
+		return nil, err
+	}
+	// a single connection, as the records are written in one transaction
+	db.SetMaxOpenConns(1)
+	tx, err := db.Begin()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &database{fileName: fileName, db: db, tx: tx, tables: make(map[string]bool), created: created}, nil
+}
+
+// createTable creates the table for the output fields in fieldSlice, and returns the writer of its records
+func (d *database) createTable(tableName string, fieldSlice []*Field, tableMode string, emptyFunc bool) (*table, error) {
+	if tableName == "" {
+		return nil, fmt.Errorf("the table name (sheetName) is empty")
+	}
+	if d.tables[strings.ToLower(tableName)] {
+		return nil, fmt.Errorf("table %s is used by more than one output", tableName)
+	}
+
+	t := &table{name: tableName, emptyFunc: emptyFunc, nextRow: defaultHeadingRow}
+	columns := make([]string, 0, len(fieldSlice))
+	names := make([]string, 0, len(fieldSlice))
+	for _, iter := range fieldSlice {
+		if !iter.hasOutput() {
+			continue
+		}
+		columnType := sqliteColumnType(iter)
+		columns = append(columns, sqliteQuote(iter.OutputName)+" "+columnType)
+		names = append(names, sqliteQuote(iter.OutputName))
+		t.funcCell = append(t.funcCell, iter.converterType == ConverterTypeFunc)
+		t.numeric = append(t.numeric, columnType != "TEXT")
+	}
+
+	statements := make([]string, 0, 2)
+	if tableMode == TableModeAppend {
+		statements = append(statements, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", sqliteQuote(tableName), strings.Join(columns, ", ")))
+	} else {
+		statements = append(statements, "DROP TABLE IF EXISTS "+sqliteQuote(tableName),
+			fmt.Sprintf("CREATE TABLE %s (%s)", sqliteQuote(tableName), strings.Join(columns, ", ")))
+	}
+	for _, iter := range statements {
+		if _, err := d.tx.Exec(iter); err != nil {
+			return nil, fmt.Errorf("table %s: %w", tableName, err)
+		}
+	}
+
+	var err error
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	t.stmt, err = d.tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", sqliteQuote(tableName), strings.Join(names, ", "), placeholders))
+	if err != nil {
+		return nil, fmt.Errorf("table %s: %w", tableName, err)
+	}
+	d.tables[strings.ToLower(tableName)] = true
+	return t, nil
+}
+
+// save commits the records written into the tables
+func (d *database) save() error {
+	err := d.tx.Commit()
+	d.done = true
+	if err == nil {
+		err = d.db.Close()
+	}
+	if err != nil {
+		return &Error{Kind: ErrOutputWrite, File: d.fileName, Err: err}
+	}
+	d.saved = true
+	return nil
+}
+
+// close rolls back the records if the database is not saved
+func (d *database) close() {
+	if !d.done {
+		_ = d.tx.Rollback()
+		d.done = true
+	}
+	_ = d.db.Close()
+	if d.created && !d.saved {
+		_ = os.Remove(d.fileName)
+	}
+}
+
+// table writes the records into a table of the sqlite output
+type table struct {
+	name      string
+	stmt      *sql.Stmt
+	funcCell  []bool
+	numeric   []bool // the INTEGER and REAL columns, where the empty values are written as NULL
+	emptyFunc bool   // write NULL instead of the formula for the func fields
+	nextRow   int
+}
+
+// AddRow inserts a record into the table, the sheetName is not applicable
+func (t *table) AddRow(sheetName string, data []interface{}) error {
+	if len(data) != len(t.funcCell) {
+		return fmt.Errorf("number of data items (%d) does not equal the number of columns (%d)", len(data), len(t.funcCell))
+	}
+
+	t.nextRow++
+	values := make([]interface{}, 0, len(data))
+	for i, iter := range data {
+		switch {
+		case t.funcCell[i] && t.emptyFunc:
+			values = append(values, nil)
+		case t.funcCell[i]:
+			values = append(values, funcCellValue(iter, t.nextRow))
+		case t.numeric[i] && iter == "":
+			values = append(values, nil)
+		default:
+			values = append(values, iter)
+		}
+	}
+	_, err := t.stmt.Exec(values...)
+	return err
+}
+
+// Flush does nothing as the records are committed when the database is saved
+func (t *table) Flush() error {
+	return nil
+}
@@ -0,0 +1,162 @@
+package qc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// subfilePreset is a built-in way to split the subfile values, selected by the preset setting of the subfile
+type subfilePreset struct {
+	separator string
+	minParts  int            // the values with fewer parts are skipped
+	parts     map[string]int // the part of the value fields by their output names
+}
+
+// subfilePresets are the built-in presets by name
+var subfilePresets = map[string]*subfilePreset{
+	// the Jira "Log Work" field format: "comment;date;user id;total seconds", the comment may include `;`
+	"JiraLogTime": {
+		separator: ";",
+		minParts:  4,
+		parts:     map[string]int{"Hours": -1, "Reporter": -2, "Date": -3},
+	},
+}
+
+// subfileValuePart returns the part of the subfile value referred by the input name, which is either value
+// for the whole value, or value[N] for the Nth part counted from 1, or from the end if N is negative.
+// The ok is false if the input name doesn't refer to the subfile value
+func subfileValuePart(inputName string) (part int, ok bool, err error) {
+	if inputName == "value" {
+		return 0, true, nil
+	}
+	if !strings.HasPrefix(inputName, "value[") || !strings.HasSuffix(inputName, "]") {
+		return 0, false, nil
+	}
+	part, err = strconv.Atoi(inputName[len("value[") : len(inputName)-1])
+	if err != nil || part == 0 {
+		return 0, true, fmt.Errorf("invalid part %s, should be a number starting from 1, or from -1 for the last part", inputName)
+	}
+	return part, true, nil
+}
+
+// isSubfileValue reports whether the input name refers to the subfile value
+func isSubfileValue(inputName string) bool {
+	_, ok, _ := subfileValuePart(inputName)
+	return ok
+}
+
+// masterOutputName returns the output name of the master field referred by the input name of a subfile field,
+// e.g. @Server Type, to take the converted value of the master field. The ok is false if the input name
+// doesn't refer to a master output field
+func masterOutputName(inputName string) (name string, ok bool) {
+	if !strings.HasPrefix(inputName, "@") {
+		return "", false
+	}
+	return strings.TrimSpace(inputName[1:]), true
+}
+
+// resolveMasterFields finds the master output fields referred by the subfile fields in the converted master items
+func (s *SubFile) resolveMasterFields(masterFields []*Field) error {
+	for _, field := range s.fieldSlice {
+		name, ok := masterOutputName(field.InputName)
+		if !ok {
+			continue
+		}
+		if field.masterPos = getOutputFieldPos(name, masterFields); field.masterPos == -1 {
+			return &Error{Kind: ErrConfigInvalid, Field: field.InputName,
+				Err: fmt.Errorf("subfile %s: the referenced master field [%s] is not defined", s.Name, name)}
+		}
+	}
+	return nil
+}
+
+// prepareSplit resolves the separator and the parts of the value fields in the subfile, from its separator and
+// value[N] fields, or from its preset. The subfile named JiraLogTime uses the preset of the same name if neither
+// the separator nor the preset is set. The returned *Error holds the YAML path relative to the subfile.
+func (s *SubFile) prepareSplit(fieldConfigs []*FieldConfig) error {
+	presetName := s.Preset
+	if presetName == "" && s.Separator == "" {
+		if _, ok := subfilePresets[s.Name]; ok {
+			presetName = s.Name
+		}
+	}
+	var preset *subfilePreset
+	if presetName != "" {
+		if preset = subfilePresets[presetName]; preset == nil {
+			return &Error{Kind: ErrConfigInvalid, Path: "preset", Err: fmt.Errorf("unknown preset %s", presetName)}
+		}
+	}
+
+	s.separator = s.Separator
+	s.minParts = 0
+	s.parts = nil
+	if preset != nil {
+		if s.separator == "" {
+			s.separator = preset.separator
+		}
+		s.minParts = preset.minParts
+	}
+
+	for id, iter := range fieldConfigs {
+		part, ok, err := subfileValuePart(iter.Input)
+		if iter.err != nil || !ok {
+			// the incorrect field definitions are dropped by formalizeFieldConfigs
+			continue
+		}
+		path := fmt.Sprintf("fields.%d", id)
+		if err != nil {
+			return &Error{Kind: ErrConfigInvalid, Path: path, Err: err}
+		}
+		if part == 0 && preset != nil {
+			if part, ok = preset.parts[iter.Output]; !ok {
+				return &Error{Kind: ErrConfigInvalid, Path: path, Err: fmt.Errorf("unsupported output name %s for preset %s", iter.Output, presetName)}
+			}
+		}
+		if part != 0 && s.separator == "" {
+			return &Error{Kind: ErrConfigInvalid, Path: path, Err: fmt.Errorf("%s needs the separator to split the value", iter.Input)}
+		}
+		s.parts = append(s.parts, part)
+	}
+	return nil
+}
+
+// splitValue returns the parts of the subfile value for the value fields in order, the value is skipped
+// if an error is returned
+func (s *SubFile) splitValue(value string) ([]string, error) {
+	if value == "" {
+		return nil, errors.New("empty record value")
+	}
+	if s.separator == "" {
+		result := make([]string, 0, len(s.parts))
+		for range s.parts {
+			result = append(result, value)
+		}
+		return result, nil
+	}
+
+	fields := strings.Split(value, s.separator)
+	length := len(fields)
+	if length < s.minParts {
+		return nil, fmt.Errorf("invalid record format, must have %d sections", s.minParts)
+	}
+
+	result := make([]string, 0, len(s.parts))
+	for _, part := range s.parts {
+		pos := part - 1
+		if part < 0 {
+			pos = length + part
+		}
+		switch {
+		case part == 0:
+			result = append(result, value)
+		case pos >= 0 && pos < length:
+			result = append(result, fields[pos])
+		default:
+			// the missing part is left empty
+			result = append(result, "")
+		}
+	}
+	return result, nil
+}
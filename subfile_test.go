@@ -0,0 +1,61 @@
+package qc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestSplitSubfileValue(t *testing.T) {
+	var testData = []struct {
+		subFile       SubFile
+		fields        []string
+		record        string
+		expectedValue []string
+		expectedErr   error
+	}{
+		{SubFile{Name: "JiraLogTime"}, []string{"value,Hours", "value,Reporter", "value,Date"},
+			";05/Jan/21 8:45 AM;uid:1291231203;14400", []string{"14400", "uid:1291231203", "05/Jan/21 8:45 AM"}, nil},
+		{SubFile{Name: "JiraLogTime"}, []string{"value,Date"},
+			"dasdfa;sfasdf ;05/Jan/21 8:45 AM;uid:1291231203;14400", []string{"05/Jan/21 8:45 AM"}, nil},
+		{SubFile{Name: "JiraLogTime"}, []string{"value,Date"},
+			"uid:1291231203;14400", nil, errors.New("invalid record format, must have 4 sections")},
+		{SubFile{Name: "worklog", Preset: "JiraLogTime"}, []string{"Key,Key", "value,Hours"},
+			";05/Jan/21 8:45 AM;uid:1291231203;14400", []string{"14400"}, nil},
+		{SubFile{Name: "value"}, []string{"value,Test"}, ";1214400", []string{";1214400"}, nil},
+		{SubFile{Name: "value"}, []string{"value,Test"}, "", nil, errors.New("empty record value")},
+		{SubFile{Name: "ports", Separator: "/"}, []string{"value[1],Protocol", "value[-1],Port", "value[3],Extra", "value,Value"},
+			"tcp/443", []string{"tcp", "443", "", "tcp/443"}, nil},
+	}
+
+	for _, data := range testData {
+		subFile := data.subFile
+		tt.Nil(t, subFile.prepareSplit(ParseFieldConfigs(data.fields...)))
+		result, err := subFile.splitValue(data.record)
+		tt.Equal(t, data.expectedErr, err)
+		if err == nil {
+			tt.Equal(t, data.expectedValue, result)
+		}
+	}
+}
+
+func TestPrepareSubfileSplit(t *testing.T) {
+	var testData = []struct {
+		subFile  SubFile
+		fields   []string
+		expected string
+	}{
+		{SubFile{Name: "JiraLogTime"}, []string{"value,Content"}, "invalid config (path: fields.0): unsupported output name Content for preset JiraLogTime"},
+		{SubFile{Name: "worklog", Preset: "Jira"}, []string{"value,Hours"}, "invalid config (path: preset): unknown preset Jira"},
+		{SubFile{Name: "ports"}, []string{"Key,Key", "value[1],Protocol"}, "invalid config (path: fields.1): value[1] needs the separator to split the value"},
+		{SubFile{Name: "ports", Separator: "/"}, []string{"value[0],Protocol"}, "invalid config (path: fields.0): invalid part value[0], should be a number starting from 1, or from -1 for the last part"},
+	}
+
+	for _, data := range testData {
+		subFile := data.subFile
+		err := subFile.prepareSplit(ParseFieldConfigs(data.fields...))
+		tt.NotNil(t, err)
+		tt.Equal(t, data.expected, err.Error())
+	}
+}
@@ -0,0 +1,293 @@
+package qc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the config for the mistakes which otherwise only surface during a run, e.g. unknown field
+// types, lookups referring to undefined fields or filters on unknown fields. The lookup files are read to
+// check they are loadable, but no file is written. If header is not nil, the input field names are also
+// checked against it. All the problems are returned, each as an *Error with the YAML path of the setting.
+func Validate(config *CSVConvertorConfig, header []string) []error {
+	v := &validator{config: config}
+
+	v.validateLookups()
+	if config.HeaderRow < 0 {
+		v.addf("headerRow", "invalid headerRow %d, should be a row number starting from 1", config.HeaderRow)
+	}
+	if _, err := inputEncoding(config.Encoding); err != nil {
+		v.addf("encoding", "%s", err)
+	}
+	if err := checkHeaderMatch(config.HeaderMatch); err != nil {
+		v.addf("headerMatch", "%s", err)
+	}
+	v.validateInputOptions()
+	v.validateOutput("", config.Output, config.Format, config.FuncCells, config.TableMode)
+	outputs := v.validateFields("fields", config.Fields, true)
+	for id, iter := range config.Subfiles {
+		path := fmt.Sprintf("subfile.%d", id)
+		if iter.Name == "" {
+			v.addf(path+".name", "subfile name is empty")
+		}
+		if iter.Nested {
+			if format, err := outputFormat(config.Output, config.Format); err == nil && format != FormatJSONL {
+				v.addf(path+".nested", "the nested rows are only supported in the %s output", FormatJSONL)
+			}
+			if err := checkFuncCells(iter.FuncCells); err != nil {
+				v.addf(path+".funcCells", "%s", err)
+			}
+		} else {
+			v.validateOutput(path+".", iter.Output, iter.Format, iter.FuncCells, iter.TableMode)
+		}
+		subOutputs := v.validateFields(path+".fields", iter.Fields, false)
+		v.validateSubfileInputs(path+".fields", iter.Fields, outputs)
+		v.validateFilters(path+".", iter.Filters, subOutputs)
+		subFile := *iter
+		if err := subFile.prepareSplit(iter.Fields); err != nil {
+			var splitErr *Error
+			if errors.As(err, &splitErr) {
+				splitErr.Path = path + "." + splitErr.Path
+			}
+			v.problems = append(v.problems, err)
+		}
+	}
+	v.validateFilters("", config.Filters, outputs)
+	if header != nil {
+		v.validateHeader(header)
+	}
+	return v.problems
+}
+
+type validator struct {
+	config   *CSVConvertorConfig
+	lookups  map[string]bool // the names of the lookups defined in the config
+	problems []error
+}
+
+func (v *validator) addf(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, &Error{Kind: ErrConfigInvalid, Path: path, Err: fmt.Errorf(format, args...)})
+}
+
+// validateOutput checks the output settings, prefix is the YAML path of the output settings
+func (v *validator) validateOutput(prefix string, fileName string, format string, funcCells string, tableMode string) {
+	if _, err := outputFormat(fileName, format); err != nil {
+		v.addf(prefix+"format", "%s", err)
+	}
+	if err := checkFuncCells(funcCells); err != nil {
+		v.addf(prefix+"funcCells", "%s", err)
+	}
+	if err := checkTableMode(tableMode); err != nil {
+		v.addf(prefix+"tableMode", "%s", err)
+	}
+}
+
+// validateFilters checks the filters refer to the output fields, prefix is the YAML path of the filter settings
+func (v *validator) validateFilters(prefix string, filters []*Filter, outputs []string) {
+	for id, iter := range filters {
+		if !containsString(outputs, iter.Field) {
+			v.addf(fmt.Sprintf("%sfilter.%d.field", prefix, id), "filter field [%s] is not defined in the field list", iter.Field)
+		}
+	}
+}
+
+func (v *validator) validateInputOptions() {
+	options := v.config.InputOptions
+	comma, err := parseDelimiter(options.Delimiter)
+	if err != nil {
+		v.addf("inputOptions.delimiter", "%s", err)
+	}
+	comment, commentErr := parseComment(options.Comment)
+	if commentErr != nil {
+		v.addf("inputOptions.comment", "%s", commentErr)
+	} else if err == nil && comma == comment {
+		v.addf("inputOptions.comment", "the comment character %q is the same as the delimiter", options.Comment)
+	}
+	if options.SkipRows < 0 {
+		v.addf("inputOptions.skipRows", "invalid skipRows %d, should not be negative", options.SkipRows)
+	}
+}
+
+func (v *validator) validateLookups() {
+	v.lookups = make(map[string]bool)
+	for id, iter := range v.config.Lookups {
+		path := fmt.Sprintf("lookup.%d", id)
+		if iter.Name == "" {
+			v.addf(path+".name", "lookup name is empty")
+		}
+		v.lookups[iter.Name] = true
+
+		if iter.Type != "" && !isKnownType(iter.Type) {
+			v.addf(path+".type", "unknown type %s", iter.Type)
+			continue
+		}
+		if _, err := loadLookup(iter, nil); err != nil {
+			var lookupErr *Error
+			if errors.As(err, &lookupErr) {
+				lookupErr.Path = path
+			}
+			v.problems = append(v.problems, err)
+		}
+	}
+}
+
+// validateFields checks a field list and returns the output names of the fields, in which the subfile fields
+// are left out as they write no output column
+func (v *validator) validateFields(path string, fieldConfigs []*FieldConfig, master bool) []string {
+	outputs := make([]string, 0, len(fieldConfigs))
+	// the output names of all the fields, to tell the references to later fields from undefined ones
+	allOutputs := make([]string, 0, len(fieldConfigs))
+	for _, iter := range fieldConfigs {
+		if !isSubfileType(iter.Type) {
+			allOutputs = append(allOutputs, iter.Output)
+		}
+	}
+
+	for id, iter := range fieldConfigs {
+		fieldPath := fmt.Sprintf("%s.%d", path, id)
+		if iter.err != nil {
+			v.addf(fieldPath, "incorrect field format %s: %s", iter.definition, iter.err)
+			continue
+		}
+		if iter.Type != "" && !isKnownType(iter.Type) {
+			v.addf(fieldPath, "unknown type %s", iter.Type)
+		}
+		if _, err := newHeaderMatcher(iter, ""); err != nil {
+			v.addf(fieldPath, "%s", err)
+		}
+
+		ft, _ := FieldTypeConvert(iter.Type)
+		switch ft {
+		case ConverterTypeLookup:
+			v.validateLookupField(fieldPath, iter, outputs, allOutputs)
+		case ConverterTypeSubfile, ConverterTypeExplode:
+			if !master {
+				v.addf(fieldPath, "%s is not supported in the subfile fields", ft)
+			} else if len(iter.Params) == 0 {
+				v.addf(fieldPath, "missing subfile name")
+			} else if name := strings.TrimSpace(iter.Params[0]); !v.hasSubfile(name) {
+				v.addf(fieldPath, "subfile [%s] is not defined in subfile", name)
+			}
+		case ConverterTypeFunc, ConverterTypeConstantString:
+			if len(iter.Params) == 0 {
+				v.addf(fieldPath, "missing parameter for type %s", iter.Type)
+			}
+		case ConverterTypeDefault:
+			if iter.Type != "" {
+				params := make([]string, 0)
+				for _, param := range iter.Params {
+					params = append(params, strings.TrimSpace(param))
+				}
+				if _, ok, err := newCustomConverter(iter.Type, params); ok && err != nil {
+					v.addf(fieldPath, "%s", err)
+				}
+			}
+		}
+		if !isSubfileType(iter.Type) {
+			outputs = append(outputs, iter.Output)
+		}
+	}
+	return outputs
+}
+
+// isSubfileType reports whether the field type writes the values into the subfile rows
+func isSubfileType(fieldType string) bool {
+	ft, _ := FieldTypeConvert(fieldType)
+	return ft == ConverterTypeSubfile || ft == ConverterTypeExplode
+}
+
+func (v *validator) validateLookupField(path string, fc *FieldConfig, outputs []string, allOutputs []string) {
+	if len(fc.Params) != 3 {
+		v.addf(path, "invalid parameter size %d for lookup, should have: src index, map name, result index", len(fc.Params))
+		return
+	}
+
+	ref := strings.TrimSpace(fc.Params[0])
+	if !containsString(outputs, ref) {
+		if containsString(allOutputs, ref) {
+			v.addf(path, "the referenced field [%s] must be defined prior to the current field", ref)
+		} else {
+			v.addf(path, "the referenced field [%s] is not defined", ref)
+		}
+	}
+	if name := strings.TrimSpace(fc.Params[1]); !v.lookups[name] {
+		v.addf(path, "undefined lookup map %s", name)
+	}
+	if column, err := strconv.Atoi(strings.TrimSpace(fc.Params[2])); err != nil || column < 2 {
+		v.addf(path, "incorrect map index value %s, should be a number starting from 2", strings.TrimSpace(fc.Params[2]))
+	}
+}
+
+// validateSubfileInputs checks the subfile fields refer to the input fields or the output fields of the master fields
+func (v *validator) validateSubfileInputs(path string, fieldConfigs []*FieldConfig, outputs []string) {
+	for id, iter := range fieldConfigs {
+		if iter.err != nil || iter.Input == "" || isSubfileValue(iter.Input) || inputColumn(iter.Input) >= 0 {
+			continue
+		}
+		if name, ok := masterOutputName(iter.Input); ok {
+			if name == "" || !containsString(outputs, name) {
+				v.addf(fmt.Sprintf("%s.%d", path, id), "output field [%s] is not defined in the master fields", name)
+			}
+			continue
+		}
+		if !v.hasMasterInput(iter.Input) {
+			v.addf(fmt.Sprintf("%s.%d", path, id), "input field [%s] is not defined in the master fields", iter.Input)
+		}
+	}
+}
+
+func (v *validator) validateHeader(header []string) {
+	header = append([]string{}, header...)
+	trimBOM(header)
+	if !v.config.hasHeader() {
+		header = positionalHeader(len(header))
+	}
+	for id, iter := range v.config.Fields {
+		if iter.err != nil || iter.Input == "" || iter.Input == v.config.SourceFileField {
+			continue
+		}
+		if !matchHeader(header, iter, v.config.HeaderMatch) {
+			v.addf(fmt.Sprintf("fields.%d", id), "input field [%s] is not found in the header", iter.Input)
+		}
+	}
+}
+
+// matchHeader reports whether a header name matches the input field defined in fc
+func matchHeader(header []string, fc *FieldConfig, headerMatch string) bool {
+	m, err := newHeaderMatcher(fc, headerMatch)
+	if err != nil || checkHeaderMatch(headerMatch) != nil {
+		return containsString(header, fc.Input)
+	}
+	for id, iter := range header {
+		if m.match(id, iter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) hasSubfile(name string) bool {
+	for _, iter := range v.config.Subfiles {
+		if iter.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) hasMasterInput(name string) bool {
+	return name == v.config.SourceFileField || hasInputField(v.config.Fields, name)
+}
+
+// isKnownType reports whether the field type is built-in or registered
+func isKnownType(name string) bool {
+	name = strings.ToLower(name)
+	if containsString(builtinConverters, name) {
+		return true
+	}
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return registry[name] != nil
+}
@@ -0,0 +1,100 @@
+package qc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeLookupFile creates a dictionary file with the rows in sheetName
+func writeLookupFile(t *testing.T, fileName, sheetName string, rows [][]string) {
+	xlFile := excelize.NewFile()
+	xlFile.SetSheetName("Sheet1", sheetName)
+	for id, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, id+1)
+		tt.Nil(t, xlFile.SetSheetRow(sheetName, cell, &row))
+	}
+	tt.Nil(t, xlFile.SaveAs(fileName))
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "dict.xlsx")
+	writeLookupFile(t, dict, "Team", [][]string{{"Name", "Team"}, {"alice", "red"}})
+
+	config := &CSVConvertorConfig{
+		FuncCells:   "blank",
+		HeaderMatch: "fuzzy",
+		InputOptions: InputOptions{
+			Delimiter: "tab",
+			Comment:   "\t",
+			SkipRows:  -1,
+		},
+		Fields: ParseFieldConfigs(
+			"ID,ID,10,integer",
+			",Team,10,lookup,User,Team,2",
+			"Name,User,20",
+			",Team2,10,lookup,User,Team",
+			",Team3,10,lookup,User,Teams,1",
+			"Log Work,,0,subfile,JiraLogTime",
+			"Components,,0,subfile,components",
+		),
+		Subfiles: []*SubFile{{
+			Name:    "components",
+			Format:  "xls",
+			Fields:  ParseFieldConfigs("Key,Key", "value,Component", "@Team,Team", "@Area,Area"),
+			Filters: []*Filter{{Field: "Component"}, {Field: "User"}},
+		}},
+		Lookups: []*Lookup{
+			{Name: "Team", FileName: dict, SheetName: "Team"},
+			{Name: "Area", FileName: dict, SheetName: "Area"},
+		},
+		Filters: []*Filter{{Field: "Application"}, {Field: "User"}},
+	}
+
+	messages := make([]string, 0)
+	for _, iter := range Validate(config, []string{"\ufeffID", "Name", "Components"}) {
+		messages = append(messages, iter.Error())
+	}
+	tt.Equal(t, []string{
+		"unable to load lookup (file: " + dict + ", path: lookup.1): sheet Area: sheet Area is not exist",
+		"invalid config (path: headerMatch): unknown headerMatch option fuzzy, should be exact, trimmed or caseInsensitive",
+		"invalid config (path: inputOptions.comment): the comment character \"\\t\" is the same as the delimiter",
+		"invalid config (path: inputOptions.skipRows): invalid skipRows -1, should not be negative",
+		"invalid config (path: funcCells): unknown funcCells option blank, should be formula or empty",
+		"invalid config (path: fields.0): unknown type integer",
+		"invalid config (path: fields.1): the referenced field [User] must be defined prior to the current field",
+		"invalid config (path: fields.3): invalid parameter size 2 for lookup, should have: src index, map name, result index",
+		"invalid config (path: fields.4): undefined lookup map Teams",
+		"invalid config (path: fields.4): incorrect map index value 1, should be a number starting from 2",
+		"invalid config (path: fields.5): subfile [JiraLogTime] is not defined in subfile",
+		"invalid config (path: subfile.0.format): unknown output format xls",
+		"invalid config (path: subfile.0.fields.0): input field [Key] is not defined in the master fields",
+		"invalid config (path: subfile.0.fields.3): output field [Area] is not defined in the master fields",
+		"invalid config (path: subfile.0.filter.1.field): filter field [User] is not defined in the field list",
+		"invalid config (path: filter.0.field): filter field [Application] is not defined in the field list",
+		"invalid config (path: fields.5): input field [Log Work] is not found in the header",
+	}, messages)
+
+	problems := Validate(&CSVConvertorConfig{Fields: []*FieldConfig{{Input: "Team", Output: "Team", Regex: "Team ("}}}, []string{"Team"})
+	tt.Equal(t, 1, len(problems))
+	tt.Equal(t, "invalid config (path: fields.0): invalid regex Team (: error parsing regexp: missing closing ): `Team (`", problems[0].Error())
+
+	// the output name of a subfile field is no output field to refer to
+	messages = messages[:0]
+	for _, iter := range Validate(&CSVConvertorConfig{
+		Fields:   ParseFieldConfigs("Host,Host", "Ports,Ports,0,explode,ports", ",Owner,8,lookup,Ports,Team,2"),
+		Subfiles: []*SubFile{{Name: "ports", Output: "ports.csv", Fields: ParseFieldConfigs("@Ports,Ports", "value,Port")}},
+		Lookups:  []*Lookup{{Name: "Team", FileName: dict, SheetName: "Team"}},
+		Filters:  []*Filter{{Field: "Ports"}},
+	}, nil) {
+		messages = append(messages, iter.Error())
+	}
+	tt.Equal(t, []string{
+		"invalid config (path: fields.2): the referenced field [Ports] is not defined",
+		"invalid config (path: subfile.0.fields.0): output field [Ports] is not defined in the master fields",
+		"invalid config (path: filter.0.field): filter field [Ports] is not defined in the field list",
+	}, messages)
+}